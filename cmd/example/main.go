@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"matchmaking/internal/app"
 	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
 	"math/rand/v2"
 	"os"
 	"os/signal"
@@ -24,9 +25,12 @@ func main() {
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	storage := matchmaking.NewStorage()
-	service := matchmaking.NewService(logger, config.MatchmakingConfig, storage)
+	service := matchmaking.NewService(logger, config.MatchmakingConfig, storage, metrics.Noop{})
 
-	matchOutput := service.Run(ctx)
+	matchOutput, err := service.Start(ctx)
+	if err != nil {
+		panic(fmt.Errorf("failed to start matchmaking: %w", err))
+	}
 	go func() {
 		for match := range matchOutput {
 			switch match.Type {
@@ -51,7 +55,9 @@ func main() {
 				ID:    fmt.Sprintf("player-%d", i),
 				Level: rand.IntN(20),
 			}
-			service.AddPlayer(player)
+			if err := service.AddPlayer(player); err != nil {
+				logger.Error("Player not added:", slog.String("error", err.Error()))
+			}
 		}
 	}()
 
@@ -59,4 +65,10 @@ func main() {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 	<-interrupt
+	if err := service.Stop(ctx); err != nil {
+		logger.Error("Failed to stop:", slog.String("error", err.Error()))
+	}
+	if err := service.Wait(); err != nil {
+		logger.Error("Stopped with error:", slog.String("error", err.Error()))
+	}
 }
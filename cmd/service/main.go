@@ -2,20 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"golang.org/x/sync/errgroup"
+	googlegrpc "google.golang.org/grpc"
 	"log/slog"
 	"matchmaking/internal/api"
 	"matchmaking/internal/app"
+	"matchmaking/internal/events"
+	"matchmaking/internal/history"
 	"matchmaking/internal/matchmaking"
 	"matchmaking/internal/metrics"
 	"matchmaking/internal/server"
+	"matchmaking/internal/webhook"
+	"matchmaking/pkg/auth"
 	"matchmaking/pkg/grpc"
 	"matchmaking/pkg/logger"
+	"matchmaking/pkg/tracing"
+	"net/http"
 	//_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 )
 
 // main is the entry point of service
@@ -28,45 +40,208 @@ func main() {
 		panic(fmt.Errorf("failed to load config: %w", err))
 	}
 
-	// logger and metrics
-	logger := logger.NewLogger(config.LogLevel)
-	prometheusRegister := prometheusclient.DefaultRegisterer
-	metrics.RegisterOn(prometheusRegister)
-	defer metrics.UnRegisterFrom(prometheusRegister)
+	// logger and metrics, logs leave the standard output to events written there
+	logOutput := os.Stdout
+	if config.EventsConfig.StdoutEnabled() {
+		logOutput = os.Stderr
+	}
+	logger := logger.NewWriterLogger(config.LogLevel, logOutput)
+	prometheusRegister := prometheusclient.NewRegistry()
+	prometheusRegister.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	serviceMetrics := metrics.NewPrometheus(config.QueueName)
+	if err := serviceMetrics.RegisterOn(prometheusRegister); err != nil {
+		panic(fmt.Errorf("failed to register matchmaking metrics: %w", err))
+	}
+	defer serviceMetrics.UnRegisterFrom(prometheusRegister)
+
+	// tracing
+	shutdownTracing, err := tracing.Setup(ctx, config.TracingConfig)
+	if err != nil {
+		panic(fmt.Errorf("failed to setup tracing: %w", err))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
 
 	// matchmaking service
 	storage := matchmaking.NewStorage()
-	service := matchmaking.NewService(logger, config.MatchmakingConfig, storage)
-	matchOutput := service.Run(ctx)
+	service := matchmaking.NewService(logger, config.MatchmakingConfig, storage, serviceMetrics)
+	if len(config.AllocatorAddresses) > 0 {
+		allocator, err := matchmaking.NewStaticAllocator(config.AllocatorAddresses)
+		if err != nil {
+			panic(fmt.Errorf("failed to create allocator: %w", err))
+		}
+		service.SetAllocator(allocator)
+	}
+	matchOutput, err := service.Start(ctx)
+	if err != nil {
+		panic(fmt.Errorf("failed to start matchmaking: %w", err))
+	}
 
 	// grpc server
-	matchmakingServer := server.NewMatchmakingServer(logger, service)
-	grpcServer := grpc.NewGRPC(logger, config.PublicGrpcConfig).
-		AddGrpcHealthCheck().
-		AddServerImplementation(matchmakingServer.Register())
+	matchmakingServer := server.NewMatchmakingServer(logger, config.MatchmakingServerConfig, service, serviceMetrics)
+	grpcOpts := []googlegrpc.ServerOption{
+		googlegrpc.StatsHandler(otelgrpc.NewServerHandler()),
+	}
+	if config.AuthEnabled {
+		authenticator, err := auth.NewAuthenticator(config.AuthConfig)
+		if err != nil {
+			panic(fmt.Errorf("failed to create authenticator: %w", err))
+		}
+		matchmakingServer.SetAuthenticator(authenticator)
+		grpcOpts = append(grpcOpts,
+			googlegrpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+			googlegrpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+		)
+	}
+	grpcServer, err := grpc.NewGRPC(logger, config.PublicGrpcConfig, grpcOpts...)
+	if err != nil {
+		panic(fmt.Errorf("failed to create grpc server: %w", err))
+	}
+	if err := grpcServer.RegisterMetricsOn(prometheusRegister); err != nil {
+		panic(fmt.Errorf("failed to register grpc metrics: %w", err))
+	}
+	defer grpcServer.UnRegisterFrom(prometheusRegister)
+	grpcServer.AddGrpcHealthCheck().
+		AddServerImplementation(matchmakingServer.Register()).
+		AddGatewayImplementation(matchmakingServer.RegisterGateway()).
+		AddGatewayHandler(http.MethodGet, "/openapi.json", matchmakingServer.OpenAPI).
+		AddGatewayHandler(http.MethodGet, "/v1/status/ws", matchmakingServer.StatusWebSocket)
 
-	// private API metrics
+	// private API metrics and admin
 	privateApiBuilder := api.NewPrivateApi(logger, config.PrivateApiConfig)
-	privateApiBuilder.RegisterPrivateRoutes()
+	privateApiBuilder.SetReadinessCheck(service.Ready)
+	privateApiBuilder.SetHealthDetails(func() (any, error) {
+		health := service.Health()
+		return health, health.Err()
+	})
+	privateApiBuilder.RegisterPrivateRoutes(prometheusRegister)
+	privateApiBuilder.RegisterRoutesFunc(api.NewAdminApi(logger, config.PrivateApiConfig, service).RegisterRoutes)
 	privateApi := privateApiBuilder.Build()
 
+	// event publishers with webhook targets and the match history get their own copy of sessions
+	var sessionConsumers []func(sessions <-chan matchmaking.MatchSession) error
+	publishers, err := events.NewConfiguredFanout(logger, config.EventsConfig, config.QueueName, serviceMetrics)
+	if err != nil {
+		panic(fmt.Errorf("failed to create event publishers: %w", err))
+	}
+	if config.WebhookConfig.Enabled() {
+		sink, err := webhook.NewSink(logger, config.WebhookConfig, serviceMetrics)
+		if err != nil {
+			panic(fmt.Errorf("failed to create webhook sink: %w", err))
+		}
+		sink.AddTo(publishers)
+	}
+	if !publishers.Empty() {
+		sessionConsumers = append(sessionConsumers, func(sessions <-chan matchmaking.MatchSession) error {
+			return publishers.Run(ctx, sessions)
+		})
+	}
+	if config.HistoryConfig.Enabled() {
+		store, err := history.Open(logger, config.HistoryConfig, config.QueueName)
+		if err != nil {
+			panic(fmt.Errorf("failed to open match history: %w", err))
+		}
+		defer store.Close()
+		matchmakingServer.SetHistory(store)
+		sessionConsumers = append(sessionConsumers, func(sessions <-chan matchmaking.MatchSession) error {
+			return store.Run(ctx, sessions)
+		})
+	}
+	statusOutput := matchOutput
+	consumersFlushed := make(chan struct{})
+	if len(sessionConsumers) > 0 {
+		outputs := matchmaking.FanOut(matchOutput, len(sessionConsumers)+1, config.QueueSize)
+		statusOutput = outputs[0]
+		consumers := errgroup.Group{}
+		for i, consume := range sessionConsumers {
+			consumers.Go(func() error {
+				return consume(outputs[i+1])
+			})
+		}
+		go func() {
+			defer close(consumersFlushed)
+			if err := consumers.Wait(); err != nil {
+				logger.ErrorContext(ctx, "session consumers stopped", slog.String("error", err.Error()))
+			}
+		}()
+	} else {
+		close(consumersFlushed)
+	}
+
 	// run servers
 	group, errCtx := errgroup.WithContext(ctx)
-	group.Go(func() error { return matchmakingServer.RunStatusUpdater(errCtx, matchOutput) })
+	statusFlushed := make(chan struct{})
+	go func() {
+		defer close(statusFlushed)
+		if err := matchmakingServer.RunStatusUpdater(ctx, statusOutput); err != nil {
+			logger.ErrorContext(ctx, "status updater stopped", slog.String("error", err.Error()))
+		}
+	}()
 	group.Go(func() error {
-		return privateApi.ListenAndServe()
+		err := privateApi.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
 	})
 	group.Go(func() error {
 		return grpcServer.ListenAndServe()
 	})
+	group.Go(func() error {
+		return grpcServer.ListenAndServeGateway(errCtx)
+	})
+	go grpcServer.RunHealthCheck(ctx, time.Second, func() error {
+		return service.Health().Err()
+	})
 
 	// graceful shutdown
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	select {
 	case <-errCtx.Done():
-		logger.ErrorContext(errCtx, "failed to start private API", slog.String("error", errCtx.Err().Error()))
-	case <-interrupt:
+		logger.ErrorContext(ctx, "server failed", slog.Any("error", context.Cause(errCtx)))
+	case <-signalCtx.Done():
 		logger.InfoContext(ctx, "shutting down")
+	case <-statusFlushed:
+		logger.ErrorContext(ctx, "matchmaking stopped, shutting down")
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), config.ShutdownTimeout())
+	defer cancelShutdown()
+
+	// stop accepting players and notify waiting ones, then flush their status updates before closing streams
+	if err := service.Stop(shutdownCtx); err != nil {
+		logger.ErrorContext(ctx, "failed to stop matchmaking", slog.String("error", err.Error()))
+	}
+	select {
+	case <-statusFlushed:
+	case <-shutdownCtx.Done():
+		logger.ErrorContext(ctx, "status updates are not flushed", slog.String("error", shutdownCtx.Err().Error()))
+	}
+	matchmakingServer.Close()
+	select {
+	case <-consumersFlushed:
+	case <-shutdownCtx.Done():
+		logger.ErrorContext(ctx, "webhooks and events are not flushed", slog.String("error", shutdownCtx.Err().Error()))
+	}
+
+	if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+		logger.ErrorContext(ctx, "failed to stop grpc server", slog.String("error", err.Error()))
+	}
+	if err := privateApi.Shutdown(shutdownCtx); err != nil {
+		logger.ErrorContext(ctx, "failed to stop private API", slog.String("error", err.Error()))
+	}
+	if err := group.Wait(); err != nil {
+		logger.ErrorContext(ctx, "server stopped with error", slog.String("error", err.Error()))
+	}
+	cancelFunc()
+	// webhooks left after the timeout go to the dead-letter file
+	<-consumersFlushed
+	if err := service.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		logger.ErrorContext(ctx, "matchmaking stopped with error", slog.String("error", err.Error()))
 	}
+	logger.InfoContext(ctx, "stopped")
 }
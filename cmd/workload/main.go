@@ -3,12 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/sethvargo/go-envconfig"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"log/slog"
 	gen "matchmaking/generated/grpc"
 	"matchmaking/internal/matchmaking"
+	"matchmaking/pkg/auth"
+	grpcserver "matchmaking/pkg/grpc"
 	"matchmaking/pkg/logger"
 	"math/rand/v2"
 	"os"
@@ -24,6 +29,12 @@ type Config struct {
 	MaxLevel                int32  `env:"MAX_LEVEL, default=9"`
 	MaxDelayBeforeAddPlayer int    `env:"MAX_DELAY_BEFORE_ADD_PLAYER, default=1000"`
 	LogLevel                string `env:"LOG_LEVEL, default=DEBUG"`
+	AuthHmacSecret          string `env:"AUTH_HMAC_SECRET"`
+	TlsEnabled              bool   `env:"WORKLOAD_TLS, default=false"`
+	TlsCAFile               string `env:"WORKLOAD_TLS_CA_FILE"`
+	TlsCertFile             string `env:"WORKLOAD_TLS_CERT_FILE"`
+	TlsKeyFile              string `env:"WORKLOAD_TLS_KEY_FILE"`
+	TlsServerName           string `env:"WORKLOAD_TLS_SERVER_NAME"`
 }
 
 // main this is a workload generator for the matchmaking service
@@ -40,7 +51,13 @@ func main() {
 	logger := logger.NewLogger(conf.LogLevel)
 	slog.SetDefault(logger)
 
-	conn, err := grpc.NewClient(conf.ServerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := transportCredentials(conf)
+	if err != nil {
+		logger.Error("could not load tls config:", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	conn, err := grpc.NewClient(conf.ServerAddr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		logger.Error("could not connect to server:", slog.String("error", err.Error()))
 		os.Exit(1)
@@ -61,22 +78,29 @@ func main() {
 				}
 				logger.DebugContext(ctx, "adding player", slog.String("player_id", player.Id), slog.Int("level", int(player.Level)))
 
-				_, err = client.AddPlayer(ctx, &gen.AddPlayerRequest{Players: []*gen.PlayerData{&player}})
+				playerCtx, err := authContext(ctx, conf.AuthHmacSecret, player.Id)
+				if err != nil {
+					logger.ErrorContext(ctx, "could not sign token:", slog.String("player_id", player.Id), slog.String("error", err.Error()))
+					continue
+				}
+
+				_, err = client.AddPlayer(playerCtx, &gen.AddPlayerRequest{Players: []*gen.PlayerData{&player}})
 				if err != nil {
 					logger.ErrorContext(ctx, "could not add player:", slog.String("player_id", player.Id), slog.String("error", err.Error()))
 					continue
 				}
 
-				statusCtx, statusCancel := context.WithTimeout(ctx, time.Minute*5)
+				statusCtx, statusCancel := context.WithTimeout(playerCtx, time.Minute*5)
 				status, err := client.Status(statusCtx, &gen.StatusRequest{PlayerId: player.Id})
 				if err != nil {
 					logger.ErrorContext(ctx, "could not get status:", slog.String("player_id", player.Id), slog.String("error", err.Error()))
+					statusCancel()
 					continue
 				}
 
 				go func() {
 					defer func() {
-						closeStream(status, player)
+						closeStream(status, player.Id)
 						statusCancel()
 						wg.Done()
 					}()
@@ -86,7 +110,7 @@ func main() {
 					if n < conf.PercentToRemove { // % chance to remove player
 						go func() {
 							time.Sleep(time.Second * time.Duration(rand.IntN(30)))
-							_, err := client.RemovePlayer(ctx, &gen.RemovePlayerRequest{Players: []*gen.PlayerData{&player}})
+							_, err := client.RemovePlayer(playerCtx, &gen.RemovePlayerRequest{Players: []*gen.PlayerData{&player}})
 							if err != nil {
 								logger.ErrorContext(ctx, "could not remove player:", slog.String("player_id", player.Id), slog.String("error", err.Error()))
 							}
@@ -113,9 +137,42 @@ func main() {
 	wg.Wait()
 }
 
-func closeStream(status grpc.ServerStreamingClient[gen.StatusResponse], player gen.PlayerData) {
+// authContext attaches a token signed for the player when authentication is configured
+func authContext(ctx context.Context, secret string, playerID string) (context.Context, error) {
+	if secret == "" {
+		return ctx, nil
+	}
+
+	token, err := auth.SignHmac(secret, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   playerID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), nil
+}
+
+func closeStream(status grpc.ServerStreamingClient[gen.StatusResponse], playerID string) {
 	err := status.CloseSend()
 	if err != nil {
-		slog.Error("failed to close status stream:", slog.String("player_id", player.Id), slog.String("error", err.Error()))
+		slog.Error("failed to close status stream:", slog.String("player_id", playerID), slog.String("error", err.Error()))
 	}
 }
+
+// transportCredentials returns TLS credentials when a CA or client certificate is configured
+func transportCredentials(conf Config) (credentials.TransportCredentials, error) {
+	if !conf.TlsEnabled && conf.TlsCAFile == "" && conf.TlsCertFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig, err := grpcserver.NewClientTlsConfig(conf.TlsCAFile, conf.TlsCertFile, conf.TlsKeyFile, conf.TlsServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
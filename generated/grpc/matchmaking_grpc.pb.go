@@ -19,9 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Matchmaking_AddPlayer_FullMethodName    = "/matchmaking.Matchmaking/AddPlayer"
-	Matchmaking_RemovePlayer_FullMethodName = "/matchmaking.Matchmaking/RemovePlayer"
-	Matchmaking_Status_FullMethodName       = "/matchmaking.Matchmaking/Status"
+	Matchmaking_AddPlayer_FullMethodName         = "/matchmaking.Matchmaking/AddPlayer"
+	Matchmaking_RemovePlayer_FullMethodName      = "/matchmaking.Matchmaking/RemovePlayer"
+	Matchmaking_Status_FullMethodName            = "/matchmaking.Matchmaking/Status"
+	Matchmaking_WatchMatches_FullMethodName      = "/matchmaking.Matchmaking/WatchMatches"
+	Matchmaking_AckMatches_FullMethodName        = "/matchmaking.Matchmaking/AckMatches"
+	Matchmaking_GetMatch_FullMethodName          = "/matchmaking.Matchmaking/GetMatch"
+	Matchmaking_ListPlayerMatches_FullMethodName = "/matchmaking.Matchmaking/ListPlayerMatches"
+	Matchmaking_BlockPlayers_FullMethodName      = "/matchmaking.Matchmaking/BlockPlayers"
+	Matchmaking_UnblockPlayers_FullMethodName    = "/matchmaking.Matchmaking/UnblockPlayers"
+	Matchmaking_GetBlockList_FullMethodName      = "/matchmaking.Matchmaking/GetBlockList"
 )
 
 // MatchmakingClient is the client API for Matchmaking service.
@@ -31,6 +38,18 @@ type MatchmakingClient interface {
 	AddPlayer(ctx context.Context, in *AddPlayerRequest, opts ...grpc.CallOption) (*AddPlayerResponse, error)
 	RemovePlayer(ctx context.Context, in *RemovePlayerRequest, opts ...grpc.CallOption) (*RemovePlayerResponse, error)
 	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusResponse], error)
+	// WatchMatches streams match events to a backend consumer, events are sent again until acknowledged.
+	// A consumer keeps up to WATCH_PENDING_SIZE unacknowledged events, the oldest one is dropped to keep a new one
+	// and the next sent event counts the drops in its dropped field.
+	WatchMatches(ctx context.Context, in *WatchMatchesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MatchEvent], error)
+	AckMatches(ctx context.Context, in *AckMatchesRequest, opts ...grpc.CallOption) (*AckMatchesResponse, error)
+	GetMatch(ctx context.Context, in *GetMatchRequest, opts ...grpc.CallOption) (*Match, error)
+	// ListPlayerMatches lists found matches of the player, the newest first
+	ListPlayerMatches(ctx context.Context, in *ListPlayerMatchesRequest, opts ...grpc.CallOption) (*ListPlayerMatchesResponse, error)
+	// BlockPlayers adds players to the block list of the player, blocked players are never matched together
+	BlockPlayers(ctx context.Context, in *BlockPlayersRequest, opts ...grpc.CallOption) (*BlockList, error)
+	UnblockPlayers(ctx context.Context, in *UnblockPlayersRequest, opts ...grpc.CallOption) (*BlockList, error)
+	GetBlockList(ctx context.Context, in *GetBlockListRequest, opts ...grpc.CallOption) (*BlockList, error)
 }
 
 type matchmakingClient struct {
@@ -80,6 +99,85 @@ func (c *matchmakingClient) Status(ctx context.Context, in *StatusRequest, opts
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Matchmaking_StatusClient = grpc.ServerStreamingClient[StatusResponse]
 
+func (c *matchmakingClient) WatchMatches(ctx context.Context, in *WatchMatchesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MatchEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Matchmaking_ServiceDesc.Streams[1], Matchmaking_WatchMatches_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchMatchesRequest, MatchEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Matchmaking_WatchMatchesClient = grpc.ServerStreamingClient[MatchEvent]
+
+func (c *matchmakingClient) AckMatches(ctx context.Context, in *AckMatchesRequest, opts ...grpc.CallOption) (*AckMatchesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AckMatchesResponse)
+	err := c.cc.Invoke(ctx, Matchmaking_AckMatches_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakingClient) GetMatch(ctx context.Context, in *GetMatchRequest, opts ...grpc.CallOption) (*Match, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Match)
+	err := c.cc.Invoke(ctx, Matchmaking_GetMatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakingClient) ListPlayerMatches(ctx context.Context, in *ListPlayerMatchesRequest, opts ...grpc.CallOption) (*ListPlayerMatchesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPlayerMatchesResponse)
+	err := c.cc.Invoke(ctx, Matchmaking_ListPlayerMatches_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakingClient) BlockPlayers(ctx context.Context, in *BlockPlayersRequest, opts ...grpc.CallOption) (*BlockList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlockList)
+	err := c.cc.Invoke(ctx, Matchmaking_BlockPlayers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakingClient) UnblockPlayers(ctx context.Context, in *UnblockPlayersRequest, opts ...grpc.CallOption) (*BlockList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlockList)
+	err := c.cc.Invoke(ctx, Matchmaking_UnblockPlayers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchmakingClient) GetBlockList(ctx context.Context, in *GetBlockListRequest, opts ...grpc.CallOption) (*BlockList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlockList)
+	err := c.cc.Invoke(ctx, Matchmaking_GetBlockList_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MatchmakingServer is the server API for Matchmaking service.
 // All implementations must embed UnimplementedMatchmakingServer
 // for forward compatibility.
@@ -87,6 +185,18 @@ type MatchmakingServer interface {
 	AddPlayer(context.Context, *AddPlayerRequest) (*AddPlayerResponse, error)
 	RemovePlayer(context.Context, *RemovePlayerRequest) (*RemovePlayerResponse, error)
 	Status(*StatusRequest, grpc.ServerStreamingServer[StatusResponse]) error
+	// WatchMatches streams match events to a backend consumer, events are sent again until acknowledged.
+	// A consumer keeps up to WATCH_PENDING_SIZE unacknowledged events, the oldest one is dropped to keep a new one
+	// and the next sent event counts the drops in its dropped field.
+	WatchMatches(*WatchMatchesRequest, grpc.ServerStreamingServer[MatchEvent]) error
+	AckMatches(context.Context, *AckMatchesRequest) (*AckMatchesResponse, error)
+	GetMatch(context.Context, *GetMatchRequest) (*Match, error)
+	// ListPlayerMatches lists found matches of the player, the newest first
+	ListPlayerMatches(context.Context, *ListPlayerMatchesRequest) (*ListPlayerMatchesResponse, error)
+	// BlockPlayers adds players to the block list of the player, blocked players are never matched together
+	BlockPlayers(context.Context, *BlockPlayersRequest) (*BlockList, error)
+	UnblockPlayers(context.Context, *UnblockPlayersRequest) (*BlockList, error)
+	GetBlockList(context.Context, *GetBlockListRequest) (*BlockList, error)
 	mustEmbedUnimplementedMatchmakingServer()
 }
 
@@ -106,6 +216,27 @@ func (UnimplementedMatchmakingServer) RemovePlayer(context.Context, *RemovePlaye
 func (UnimplementedMatchmakingServer) Status(*StatusRequest, grpc.ServerStreamingServer[StatusResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method Status not implemented")
 }
+func (UnimplementedMatchmakingServer) WatchMatches(*WatchMatchesRequest, grpc.ServerStreamingServer[MatchEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMatches not implemented")
+}
+func (UnimplementedMatchmakingServer) AckMatches(context.Context, *AckMatchesRequest) (*AckMatchesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AckMatches not implemented")
+}
+func (UnimplementedMatchmakingServer) GetMatch(context.Context, *GetMatchRequest) (*Match, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMatch not implemented")
+}
+func (UnimplementedMatchmakingServer) ListPlayerMatches(context.Context, *ListPlayerMatchesRequest) (*ListPlayerMatchesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPlayerMatches not implemented")
+}
+func (UnimplementedMatchmakingServer) BlockPlayers(context.Context, *BlockPlayersRequest) (*BlockList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockPlayers not implemented")
+}
+func (UnimplementedMatchmakingServer) UnblockPlayers(context.Context, *UnblockPlayersRequest) (*BlockList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnblockPlayers not implemented")
+}
+func (UnimplementedMatchmakingServer) GetBlockList(context.Context, *GetBlockListRequest) (*BlockList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockList not implemented")
+}
 func (UnimplementedMatchmakingServer) mustEmbedUnimplementedMatchmakingServer() {}
 func (UnimplementedMatchmakingServer) testEmbeddedByValue()                     {}
 
@@ -174,6 +305,125 @@ func _Matchmaking_Status_Handler(srv interface{}, stream grpc.ServerStream) erro
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Matchmaking_StatusServer = grpc.ServerStreamingServer[StatusResponse]
 
+func _Matchmaking_WatchMatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMatchesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatchmakingServer).WatchMatches(m, &grpc.GenericServerStream[WatchMatchesRequest, MatchEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Matchmaking_WatchMatchesServer = grpc.ServerStreamingServer[MatchEvent]
+
+func _Matchmaking_AckMatches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckMatchesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakingServer).AckMatches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaking_AckMatches_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakingServer).AckMatches(ctx, req.(*AckMatchesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaking_GetMatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakingServer).GetMatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaking_GetMatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakingServer).GetMatch(ctx, req.(*GetMatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaking_ListPlayerMatches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPlayerMatchesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakingServer).ListPlayerMatches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaking_ListPlayerMatches_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakingServer).ListPlayerMatches(ctx, req.(*ListPlayerMatchesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaking_BlockPlayers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockPlayersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakingServer).BlockPlayers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaking_BlockPlayers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakingServer).BlockPlayers(ctx, req.(*BlockPlayersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaking_UnblockPlayers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnblockPlayersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakingServer).UnblockPlayers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaking_UnblockPlayers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakingServer).UnblockPlayers(ctx, req.(*UnblockPlayersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Matchmaking_GetBlockList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchmakingServer).GetBlockList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Matchmaking_GetBlockList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchmakingServer).GetBlockList(ctx, req.(*GetBlockListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Matchmaking_ServiceDesc is the grpc.ServiceDesc for Matchmaking service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -189,6 +439,30 @@ var Matchmaking_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemovePlayer",
 			Handler:    _Matchmaking_RemovePlayer_Handler,
 		},
+		{
+			MethodName: "AckMatches",
+			Handler:    _Matchmaking_AckMatches_Handler,
+		},
+		{
+			MethodName: "GetMatch",
+			Handler:    _Matchmaking_GetMatch_Handler,
+		},
+		{
+			MethodName: "ListPlayerMatches",
+			Handler:    _Matchmaking_ListPlayerMatches_Handler,
+		},
+		{
+			MethodName: "BlockPlayers",
+			Handler:    _Matchmaking_BlockPlayers_Handler,
+		},
+		{
+			MethodName: "UnblockPlayers",
+			Handler:    _Matchmaking_UnblockPlayers_Handler,
+		},
+		{
+			MethodName: "GetBlockList",
+			Handler:    _Matchmaking_GetBlockList_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -196,6 +470,11 @@ var Matchmaking_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Matchmaking_Status_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchMatches",
+			Handler:       _Matchmaking_WatchMatches_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "matchmaking.proto",
 }
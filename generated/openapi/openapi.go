@@ -0,0 +1,9 @@
+// Package openapi exposes the OpenAPI document generated from proto/matchmaking.proto.
+package openapi
+
+import _ "embed"
+
+// Spec is the OpenAPI v2 document describing the HTTP/JSON gateway.
+//
+//go:embed matchmaking.swagger.json
+var Spec []byte
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminApi inspects and controls the matchmaking queue, every request requires the admin bearer token
+type AdminApi struct {
+	logger  *slog.Logger
+	token   string
+	service *matchmaking.Service
+}
+
+type adminPlayer struct {
+	ID          string    `json:"id"`
+	Level       int       `json:"level"`
+	Created     time.Time `json:"created"`
+	WaitSeconds float64   `json:"waitSeconds"`
+}
+
+type adminPlayersRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type adminState struct {
+	State matchmaking.ServiceState `json:"state"`
+}
+
+type adminError struct {
+	Error string `json:"error"`
+}
+
+func NewAdminApi(logger *slog.Logger, config PrivateApiConfig, service *matchmaking.Service) *AdminApi {
+	return &AdminApi{
+		logger:  logger,
+		token:   config.AdminToken,
+		service: service,
+	}
+}
+
+// RegisterRoutes registers admin routes under /admin, routes are not registered without the admin token
+func (a *AdminApi) RegisterRoutes(router *mux.Router) {
+	if a.token == "" {
+		a.logger.Warn("Admin API is disabled, ADMIN_TOKEN is not set")
+		return
+	}
+
+	admin := router.PathPrefix("/admin/v1").Subrouter()
+	admin.Use(a.authenticate)
+	admin.HandleFunc("/players", a.listPlayers).Methods(http.MethodGet)
+	admin.HandleFunc("/players/remove", a.removePlayers).Methods(http.MethodPost)
+	admin.HandleFunc("/players/{id}", a.getPlayer).Methods(http.MethodGet)
+	admin.HandleFunc("/matches", a.createMatch).Methods(http.MethodPost)
+	admin.HandleFunc("/sessions", a.listSessions).Methods(http.MethodGet)
+	admin.HandleFunc("/state", a.getState).Methods(http.MethodGet)
+	admin.HandleFunc("/state", a.setState).Methods(http.MethodPut)
+}
+
+func (a *AdminApi) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			writeJson(writer, http.StatusUnauthorized, adminError{Error: "invalid admin token"})
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+func (a *AdminApi) listPlayers(writer http.ResponseWriter, _ *http.Request) {
+	storedPlayers := a.service.WaitingPlayers()
+	players := make([]adminPlayer, 0, len(storedPlayers))
+	for _, p := range storedPlayers {
+		players = append(players, toAdminPlayer(p))
+	}
+
+	writeJson(writer, http.StatusOK, players)
+}
+
+func (a *AdminApi) getPlayer(writer http.ResponseWriter, request *http.Request) {
+	player, ok := a.service.GetPlayer(mux.Vars(request)["id"])
+	if !ok {
+		writeJson(writer, http.StatusNotFound, adminError{Error: matchmaking.ErrPlayerNotFound.Error()})
+		return
+	}
+
+	writeJson(writer, http.StatusOK, toAdminPlayer(player))
+}
+
+func (a *AdminApi) removePlayers(writer http.ResponseWriter, request *http.Request) {
+	ids, ok := readPlayerIDs(writer, request)
+	if !ok {
+		return
+	}
+
+	players := make([]matchmaking.Player, 0, len(ids))
+	for _, id := range ids {
+		players = append(players, matchmaking.Player{ID: id})
+	}
+	if err := a.service.RemovePlayerContext(request.Context(), players...); err != nil {
+		writeJson(writer, http.StatusServiceUnavailable, adminError{Error: err.Error()})
+		return
+	}
+	a.logger.InfoContext(request.Context(), "Admin removed players", slog.Any("player_ids", ids))
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+func (a *AdminApi) createMatch(writer http.ResponseWriter, request *http.Request) {
+	ids, ok := readPlayerIDs(writer, request)
+	if !ok {
+		return
+	}
+
+	err := a.service.CreateMatch(request.Context(), ids...)
+	if errors.Is(err, matchmaking.ErrPlayerNotFound) {
+		writeJson(writer, http.StatusNotFound, adminError{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, matchmaking.ErrAllocationFailed) {
+		writeJson(writer, http.StatusServiceUnavailable, adminError{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeJson(writer, http.StatusBadRequest, adminError{Error: err.Error()})
+		return
+	}
+	a.logger.InfoContext(request.Context(), "Admin created match", slog.Any("player_ids", ids))
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+func (a *AdminApi) listSessions(writer http.ResponseWriter, request *http.Request) {
+	limit := 0
+	if value := request.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			writeJson(writer, http.StatusBadRequest, adminError{Error: "limit must be a non-negative number"})
+			return
+		}
+	}
+
+	sessionType := request.URL.Query().Get("type")
+	if sessionType == "" {
+		writeJson(writer, http.StatusOK, a.service.RecentSessions(limit))
+		return
+	}
+
+	sessions := make([]matchmaking.MatchSession, 0)
+	for _, session := range a.service.RecentSessions(0) {
+		if limit > 0 && len(sessions) == limit {
+			break
+		}
+		if session.Type == sessionType {
+			sessions = append(sessions, session)
+		}
+	}
+
+	writeJson(writer, http.StatusOK, sessions)
+}
+
+func (a *AdminApi) getState(writer http.ResponseWriter, _ *http.Request) {
+	writeJson(writer, http.StatusOK, adminState{State: a.service.State()})
+}
+
+func (a *AdminApi) setState(writer http.ResponseWriter, request *http.Request) {
+	var body adminState
+	if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, 1<<20)).Decode(&body); err != nil {
+		writeJson(writer, http.StatusBadRequest, adminError{Error: "invalid request body"})
+		return
+	}
+
+	if err := a.service.SetState(request.Context(), body.State); err != nil {
+		writeJson(writer, http.StatusBadRequest, adminError{Error: err.Error()})
+		return
+	}
+
+	writeJson(writer, http.StatusOK, adminState{State: a.service.State()})
+}
+
+// readPlayerIDs decodes the request body, writes bad request when there are no player IDs
+func readPlayerIDs(writer http.ResponseWriter, request *http.Request) ([]string, bool) {
+	var body adminPlayersRequest
+	if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, 1<<20)).Decode(&body); err != nil {
+		writeJson(writer, http.StatusBadRequest, adminError{Error: "invalid request body"})
+		return nil, false
+	}
+	if len(body.IDs) == 0 {
+		writeJson(writer, http.StatusBadRequest, adminError{Error: "no players provided"})
+		return nil, false
+	}
+
+	return body.IDs, true
+}
+
+func toAdminPlayer(player matchmaking.StoredPlayer) adminPlayer {
+	return adminPlayer{
+		ID:          player.ID,
+		Level:       player.Level,
+		Created:     player.Created,
+		WaitSeconds: time.Since(player.Created).Seconds(),
+	}
+}
+
+func writeJson(writer http.ResponseWriter, statusCode int, body any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(body)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAdminToken = "admin-token"
+
+var emptyLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+func newTestAdminRouter(players ...matchmaking.StoredPlayer) *mux.Router {
+	storage := matchmaking.NewStorage()
+	storage.AddPlayers(players)
+	service := matchmaking.NewService(emptyLogger, matchmaking.MatchmakingConfig{QueueSize: 10}, storage, metrics.Noop{})
+	router := mux.NewRouter()
+	NewAdminApi(emptyLogger, PrivateApiConfig{AdminToken: testAdminToken}, service).RegisterRoutes(router)
+	return router
+}
+
+func serveAdmin(router *mux.Router, method string, target string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+testAdminToken)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	// Arrange
+	router := newTestAdminRouter()
+	request := httptest.NewRequest(http.MethodGet, "/admin/v1/players", nil)
+	request.Header.Set("Authorization", "Bearer wrong")
+	recorder := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(recorder, request)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestAdminDisabledWithoutToken(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	NewAdminApi(emptyLogger, PrivateApiConfig{}, nil).RegisterRoutes(router)
+	recorder := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/v1/players", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestAdminListAndGetPlayers(t *testing.T) {
+	// Arrange
+	created := time.Now().Add(-time.Minute)
+	router := newTestAdminRouter(
+		matchmaking.StoredPlayer{Player: matchmaking.Player{ID: "2", Level: 20}, Created: created},
+		matchmaking.StoredPlayer{Player: matchmaking.Player{ID: "1", Level: 10}, Created: created},
+	)
+
+	// Act
+	list := serveAdmin(router, http.MethodGet, "/admin/v1/players", "")
+	found := serveAdmin(router, http.MethodGet, "/admin/v1/players/2", "")
+	missing := serveAdmin(router, http.MethodGet, "/admin/v1/players/3", "")
+
+	// Assert
+	var players []adminPlayer
+	assert.Equal(t, http.StatusOK, list.Code)
+	assert.NoError(t, json.Unmarshal(list.Body.Bytes(), &players))
+	assert.Len(t, players, 2)
+	assert.Equal(t, "1", players[0].ID)
+	assert.GreaterOrEqual(t, players[0].WaitSeconds, 60.0)
+	var player adminPlayer
+	assert.Equal(t, http.StatusOK, found.Code)
+	assert.NoError(t, json.Unmarshal(found.Body.Bytes(), &player))
+	assert.Equal(t, 20, player.Level)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
+
+func TestAdminCreateMatch(t *testing.T) {
+	// Arrange
+	router := newTestAdminRouter(
+		matchmaking.StoredPlayer{Player: matchmaking.Player{ID: "1", Level: 1}, Created: time.Now()},
+		matchmaking.StoredPlayer{Player: matchmaking.Player{ID: "2", Level: 90}, Created: time.Now()},
+	)
+
+	// Act
+	created := serveAdmin(router, http.MethodPost, "/admin/v1/matches", `{"ids":["1","2"]}`)
+	missing := serveAdmin(router, http.MethodPost, "/admin/v1/matches", `{"ids":["1","3"]}`)
+	empty := serveAdmin(router, http.MethodPost, "/admin/v1/matches", `{"ids":[]}`)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, created.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.Equal(t, http.StatusBadRequest, empty.Code)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"github.com/gorilla/mux"
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"log/slog"
 	"net/http"
@@ -14,6 +15,8 @@ type PrivateApi struct {
 	Router  *mux.Router
 	Address string
 	logger  *slog.Logger
+	ready   func() error
+	health  func() (any, error)
 }
 
 func NewPrivateApi(logger *slog.Logger, config PrivateApiConfig) *PrivateApi {
@@ -21,9 +24,21 @@ func NewPrivateApi(logger *slog.Logger, config PrivateApiConfig) *PrivateApi {
 		Address: config.Address,
 		Router:  mux.NewRouter(),
 		logger:  logger,
+		ready:   func() error { return nil },
+		health:  func() (any, error) { return struct{}{}, nil },
 	}
 }
 
+// SetHealthDetails sets details served by the health endpoint, it responds with 503 while the error is returned
+func (a *PrivateApi) SetHealthDetails(health func() (any, error)) {
+	a.health = health
+}
+
+// SetReadinessCheck sets the check of the readiness probe, the service is not ready while it returns an error
+func (a *PrivateApi) SetReadinessCheck(ready func() error) {
+	a.ready = ready
+}
+
 func (a *PrivateApi) Build() *http.Server {
 	return &http.Server{
 		Addr:              a.Address,
@@ -44,10 +59,12 @@ func (a *PrivateApi) Prefix(serverName string, path string) string {
 	return fmt.Sprintf("/%s/%s", serverName, path)
 }
 
-func (a *PrivateApi) RegisterPrivateRoutes() {
+// RegisterPrivateRoutes registers probes and metrics collected from the gatherer
+func (a *PrivateApi) RegisterPrivateRoutes(gatherer prometheusclient.Gatherer) {
 	a.Router.HandleFunc("/liveness", a.liveness).Methods("GET")
 	a.Router.HandleFunc("/readiness", a.readiness).Methods("GET")
-	a.Router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	a.Router.HandleFunc("/health", a.healthDetails).Methods("GET")
+	a.Router.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})).Methods("GET")
 
 	// Register pprof handlers
 	//a.Router.HandleFunc("/debug/pprof/", http.DefaultServeMux.ServeHTTP)
@@ -65,10 +82,25 @@ func (a *PrivateApi) liveness(writer http.ResponseWriter, _ *http.Request) {
 
 func (a *PrivateApi) readiness(writer http.ResponseWriter, _ *http.Request) {
 	writer.Header().Set("Content-Type", "text/plain")
+	if err := a.ready(); err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = writer.Write([]byte(err.Error()))
+		return
+	}
 	writer.WriteHeader(http.StatusOK)
 	_, _ = writer.Write([]byte("ready"))
 }
 
+func (a *PrivateApi) healthDetails(writer http.ResponseWriter, _ *http.Request) {
+	details, err := a.health()
+	statusCode := http.StatusOK
+	if err != nil {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writeJson(writer, statusCode, details)
+}
+
 func prettyAddress(address string) string {
 	if strings.HasPrefix(address, ":") {
 		return fmt.Sprintf("localhost%s", address)
@@ -1,5 +1,6 @@
 package api
 
 type PrivateApiConfig struct {
-	Address string `env:"PRIVATE_ADDRESS, default=:8081"`
+	Address    string `env:"PRIVATE_ADDRESS, default=:8081"`
+	AdminToken string `env:"ADMIN_TOKEN"`
 }
@@ -5,15 +5,34 @@ import (
 	"fmt"
 	"github.com/sethvargo/go-envconfig"
 	"matchmaking/internal/api"
+	"matchmaking/internal/events"
+	"matchmaking/internal/history"
 	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/server"
+	"matchmaking/internal/webhook"
+	"matchmaking/pkg/auth"
 	"matchmaking/pkg/grpc"
+	"matchmaking/pkg/tracing"
+	"time"
 )
 
 type Config struct {
 	matchmaking.MatchmakingConfig
 	api.PrivateApiConfig
 	grpc.PublicGrpcConfig
-	LogLevel string `env:"LOG_LEVEL, default=DEBUG"`
+	server.MatchmakingServerConfig
+	auth.AuthConfig
+	tracing.TracingConfig
+	webhook.WebhookConfig
+	events.EventsConfig
+	history.HistoryConfig
+	LogLevel               string `env:"LOG_LEVEL, default=DEBUG"`
+	ShutdownTimeoutSeconds int    `env:"SHUTDOWN_TIMEOUT_SECONDS, default=30"`
+}
+
+// ShutdownTimeout time given to flush status updates and finish running calls on shutdown
+func (c Config) ShutdownTimeout() time.Duration {
+	return time.Duration(c.ShutdownTimeoutSeconds) * time.Second
 }
 
 func NewConfig(ctx context.Context) (*Config, error) {
@@ -21,6 +40,12 @@ func NewConfig(ctx context.Context) (*Config, error) {
 	if err := envconfig.Process(ctx, &conf); err != nil {
 		return nil, fmt.Errorf("failed to process env vars: %w", err)
 	}
+	if err := conf.MatchmakingConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if err := conf.MatchmakingServerConfig.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &conf, nil
 }
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"fmt"
+	"log/slog"
+	"matchmaking/internal/metrics"
+	"slices"
+	"time"
+)
+
+type Output = string
+
+const (
+	OutputFile   Output = "file"
+	OutputStdout Output = "stdout"
+	OutputStderr Output = "stderr"
+	OutputNats   Output = "nats"
+)
+
+type EventsConfig struct {
+	EventsOutputs            []string `env:"EVENTS_OUTPUTS"`
+	EventsQueueSize          int      `env:"EVENTS_QUEUE_SIZE, default=1000"`
+	EventsFile               string   `env:"EVENTS_FILE, default=events.jsonl"`
+	EventsNatsURL            string   `env:"EVENTS_NATS_URL, default=nats://127.0.0.1:4222"`
+	EventsNatsSubjectPrefix  string   `env:"EVENTS_NATS_SUBJECT_PREFIX, default=matchmaking"`
+	EventsNatsTimeoutSeconds int      `env:"EVENTS_NATS_TIMEOUT_SECONDS, default=5"`
+}
+
+func (c EventsConfig) NatsTimeout() time.Duration {
+	return time.Duration(c.EventsNatsTimeoutSeconds) * time.Second
+}
+
+// StdoutEnabled reports whether events are written to the standard output, logs go to the standard error then
+func (c EventsConfig) StdoutEnabled() bool {
+	return slices.Contains(c.EventsOutputs, OutputStdout)
+}
+
+// NewConfiguredFanout creates a fanout of the configured outputs, it is empty when no output is configured
+func NewConfiguredFanout(logger *slog.Logger, config EventsConfig, queue string, recorder metrics.Recorder) (*Fanout, error) {
+	fanout := NewFanout(logger, queue, config.EventsQueueSize, recorder)
+	for _, output := range config.EventsOutputs {
+		switch output {
+		case OutputFile:
+			publisher, err := NewFilePublisher(config.EventsFile)
+			if err != nil {
+				return nil, err
+			}
+			fanout.Add(output, publisher)
+		case OutputStdout:
+			fanout.Add(output, NewStdoutPublisher())
+		case OutputStderr:
+			fanout.Add(output, NewStderrPublisher())
+		case OutputNats:
+			publisher, err := NewNatsPublisher(config.EventsNatsURL, config.EventsNatsSubjectPrefix, config.NatsTimeout())
+			if err != nil {
+				return nil, err
+			}
+			fanout.Add(output, publisher)
+		default:
+			return nil, fmt.Errorf("unknown events output %q", output)
+		}
+	}
+
+	return fanout, nil
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"matchmaking/internal/matchmaking"
+	"time"
+)
+
+// Event is a match session of a queue as it is published to downstream consumers
+type Event struct {
+	ID       string                `json:"id"`
+	Queue    string                `json:"queue"`
+	Type     string                `json:"type"`
+	Created  time.Time             `json:"created"`
+	Players  []matchmaking.Player  `json:"players"`
+	Endpoint *matchmaking.Endpoint `json:"endpoint,omitempty"`
+	Quality  float64               `json:"quality,omitempty"`
+}
+
+func NewEvent(queue string, session matchmaking.MatchSession) Event {
+	return Event{
+		ID:       session.ID,
+		Queue:    queue,
+		Type:     session.Type,
+		Created:  session.Created,
+		Players:  session.Players,
+		Endpoint: session.Endpoint,
+		Quality:  session.Quality,
+	}
+}
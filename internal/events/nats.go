@@ -0,0 +1,199 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NatsPublisher publishes events to a NATS compatible broker with the core text protocol,
+// every event goes to the <prefix>.<queue>.<type> subject and is confirmed with a PING round trip.
+// The connection is opened on the first event and reopened once when an event fails.
+type NatsPublisher struct {
+	l       sync.Mutex
+	address string
+	user    *url.Userinfo
+	prefix  string
+	timeout time.Duration
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+var _ EventPublisher = (*NatsPublisher)(nil)
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// NewNatsPublisher creates a publisher of the nats://[user:pass@]host:port or nats://token@host:port server
+func NewNatsPublisher(serverURL, prefix string, timeout time.Duration) (*NatsPublisher, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats url: %w", err)
+	}
+	if parsed.Scheme != "nats" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid nats url %q: expected nats://host:port", serverURL)
+	}
+
+	return &NatsPublisher{
+		address: parsed.Host,
+		user:    parsed.User,
+		prefix:  prefix,
+		timeout: timeout,
+	}, nil
+}
+
+func (p *NatsPublisher) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	subject := natsSubject(p.prefix, event.Queue, event.Type)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	// an idle connection may have been closed by the server
+	reused := p.conn != nil
+	err = p.publish(ctx, subject, payload)
+	if err != nil && reused {
+		err = p.publish(ctx, subject, payload)
+	}
+
+	return err
+}
+
+func (p *NatsPublisher) Close() error {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	return p.disconnect()
+}
+
+// publish sends the message and waits for the PONG, the connection is closed on errors
+func (p *NatsPublisher) publish(ctx context.Context, subject string, payload []byte) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	err := p.roundTrip(fmt.Appendf(nil, "PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload))
+	if err != nil {
+		_ = p.disconnect()
+	}
+	return err
+}
+
+func (p *NatsPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	p.reader = bufio.NewReader(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(p.timeout))
+	line, err := p.readLine()
+	if err != nil {
+		_ = p.disconnect()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		_ = p.disconnect()
+		return fmt.Errorf("unexpected nats greeting: %q", line)
+	}
+
+	connect := natsConnect{Name: "matchmaking", Lang: "go", Version: "1", Protocol: 1}
+	if p.user != nil {
+		if pass, ok := p.user.Password(); ok {
+			connect.User, connect.Pass = p.user.Username(), pass
+		} else {
+			connect.Token = p.user.Username()
+		}
+	}
+	options, err := json.Marshal(connect)
+	if err != nil {
+		_ = p.disconnect()
+		return err
+	}
+	if err := p.roundTrip(fmt.Appendf(nil, "CONNECT %s\r\nPING\r\n", options)); err != nil {
+		_ = p.disconnect()
+		return err
+	}
+
+	return nil
+}
+
+// roundTrip writes the commands ending with PING and reads until PONG, answering server PINGs
+func (p *NatsPublisher) roundTrip(commands []byte) error {
+	_ = p.conn.SetDeadline(time.Now().Add(p.timeout))
+	if _, err := p.conn.Write(commands); err != nil {
+		return err
+	}
+
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimPrefix(line, "-ERR "), "'"))
+		}
+		// +OK and INFO updates need no answer
+	}
+}
+
+func (p *NatsPublisher) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *NatsPublisher) disconnect() error {
+	if p.conn == nil {
+		return nil
+	}
+
+	err := p.conn.Close()
+	p.conn = nil
+	p.reader = nil
+	return err
+}
+
+// natsSubject appends queue and type tokens to the prefix, characters which separate or wildcard tokens are replaced
+func natsSubject(prefix, queue, eventType string) string {
+	replacer := strings.NewReplacer(".", "_", " ", "_", "*", "_", ">", "_")
+	subject := replacer.Replace(queue) + "." + replacer.Replace(eventType)
+	if prefix == "" {
+		return subject
+	}
+
+	return prefix + "." + subject
+}
@@ -0,0 +1,165 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"matchmaking/internal/matchmaking"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsStandIn is an in-process server speaking enough of the NATS protocol to receive published messages
+type natsStandIn struct {
+	listener net.Listener
+	l        sync.Mutex
+	connects []string
+	messages map[string][]string
+	// reject answers every PUB with -ERR
+	reject bool
+}
+
+func newNatsStandIn(t *testing.T) *natsStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &natsStandIn{listener: listener, messages: make(map[string][]string)}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *natsStandIn) url(userinfo string) string {
+	return fmt.Sprintf("nats://%s%s", userinfo, s.listener.Addr())
+}
+
+func (s *natsStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	_, _ = fmt.Fprintf(conn, "INFO {\"server_id\":\"stand-in\",\"max_payload\":1048576}\r\n")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			s.l.Lock()
+			s.connects = append(s.connects, strings.TrimSpace(strings.TrimPrefix(line, "CONNECT")))
+			s.l.Unlock()
+		case "PING":
+			_, _ = conn.Write([]byte("PONG\r\n"))
+		case "PUB":
+			var size int
+			_, _ = fmt.Sscanf(fields[len(fields)-1], "%d", &size)
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.l.Lock()
+			reject := s.reject
+			if !reject {
+				s.messages[fields[1]] = append(s.messages[fields[1]], string(payload[:size]))
+			}
+			s.l.Unlock()
+			if reject {
+				_, _ = conn.Write([]byte("-ERR 'Permissions Violation'\r\n"))
+			}
+		}
+	}
+}
+
+func (s *natsStandIn) received(subject string) []string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.messages[subject]
+}
+
+func (s *natsStandIn) connected() []string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.connects
+}
+
+func TestNatsPublisher(t *testing.T) {
+	// Arrange
+	server := newNatsStandIn(t)
+	publisher, err := NewNatsPublisher(server.url("user:pass@"), "matchmaking", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+	event := NewEvent("ranked.eu", matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "1"}))
+
+	// Act
+	first := publisher.Publish(t.Context(), event)
+	second := publisher.Publish(t.Context(), event)
+
+	// Assert
+	require.NoError(t, first)
+	require.NoError(t, second)
+	messages := server.received("matchmaking.ranked_eu.matched")
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages[0], event.ID)
+	connects := server.connected()
+	require.Len(t, connects, 1)
+	assert.Contains(t, connects[0], `"user":"user"`)
+}
+
+func TestNatsPublisherError(t *testing.T) {
+	// Arrange
+	server := newNatsStandIn(t)
+	server.reject = true
+	publisher, err := NewNatsPublisher(server.url(""), "", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	// Act
+	err = publisher.Publish(t.Context(), NewEvent("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeAdded)))
+
+	// Assert
+	assert.EqualError(t, err, "Permissions Violation")
+}
+
+func TestNatsPublisherReconnects(t *testing.T) {
+	// Arrange
+	server := newNatsStandIn(t)
+	publisher, err := NewNatsPublisher(server.url(""), "", time.Second)
+	require.NoError(t, err)
+	defer publisher.Close()
+	event := NewEvent("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeAdded))
+	require.NoError(t, publisher.Publish(t.Context(), event))
+
+	// Act
+	_ = publisher.conn.Close()
+	err = publisher.Publish(t.Context(), event)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, server.received("default.added"), 2)
+}
+
+func TestNewNatsPublisherInvalidURL(t *testing.T) {
+	// Act
+	_, err := NewNatsPublisher("http://localhost:4222", "", time.Second)
+
+	// Assert
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"os"
+	"sync"
+)
+
+var errQueueFull = errors.New("publisher queue is full")
+
+// EventPublisher publishes session events to downstream consumers
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// DropHandler is implemented by publishers which keep events dropped because their queue is full,
+// e.g. in a dead-letter file
+type DropHandler interface {
+	Dropped(ctx context.Context, event Event, err error)
+}
+
+// Fanout publishes every session of the channel to all added publishers,
+// each publisher has its own queue, so a slow or failing one does not delay the others
+type Fanout struct {
+	logger     *slog.Logger
+	queue      string
+	size       int
+	metrics    metrics.Recorder
+	publishers []namedPublisher
+}
+
+type namedPublisher struct {
+	name      string
+	publisher EventPublisher
+	events    chan Event
+}
+
+// NewFanout creates a fanout of the queue sessions, every publisher keeps up to size events waiting
+func NewFanout(logger *slog.Logger, queue string, size int, recorder metrics.Recorder) *Fanout {
+	return &Fanout{
+		logger:  logger,
+		queue:   queue,
+		size:    max(size, 1),
+		metrics: recorder,
+	}
+}
+
+// Add adds the publisher, the name labels its logs and metrics
+func (f *Fanout) Add(name string, publisher EventPublisher) *Fanout {
+	return f.AddSized(name, publisher, f.size)
+}
+
+// AddSized adds the publisher keeping up to size events waiting instead of the fanout size
+func (f *Fanout) AddSized(name string, publisher EventPublisher, size int) *Fanout {
+	f.publishers = append(f.publishers, namedPublisher{
+		name:      name,
+		publisher: publisher,
+		events:    make(chan Event, max(size, 1)),
+	})
+	return f
+}
+
+// Empty reports whether there is no publisher to run
+func (f *Fanout) Empty() bool {
+	return len(f.publishers) == 0
+}
+
+// Run publishes sessions until the channel is closed and queued events are published, then closes publishers
+func (f *Fanout) Run(ctx context.Context, sessions <-chan matchmaking.MatchSession) error {
+	wg := sync.WaitGroup{}
+	for _, p := range f.publishers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range p.events {
+				if err := p.publisher.Publish(ctx, event); err != nil {
+					f.failed(ctx, p, event, err)
+				}
+			}
+		}()
+	}
+
+	for session := range sessions {
+		event := NewEvent(f.queue, session)
+		for _, p := range f.publishers {
+			select {
+			case p.events <- event:
+			default:
+				f.failed(ctx, p, event, errQueueFull)
+				if handler, ok := p.publisher.(DropHandler); ok {
+					handler.Dropped(ctx, event, errQueueFull)
+				}
+			}
+		}
+	}
+
+	errs := make([]error, 0, len(f.publishers))
+	for _, p := range f.publishers {
+		close(p.events)
+	}
+	wg.Wait()
+	for _, p := range f.publishers {
+		errs = append(errs, p.publisher.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *Fanout) failed(ctx context.Context, p namedPublisher, event Event, err error) {
+	f.metrics.EventPublishFailed(p.name)
+	f.logger.WarnContext(ctx, "Event not published", slog.String("publisher", p.name), slog.String("event_id", event.ID), slog.String("error", err.Error()))
+}
+
+// WriterPublisher writes events as JSON lines
+type WriterPublisher struct {
+	l      sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+var _ EventPublisher = (*WriterPublisher)(nil)
+
+// NewStdoutPublisher writes events to the standard output
+func NewStdoutPublisher() *WriterPublisher {
+	return &WriterPublisher{writer: os.Stdout}
+}
+
+// NewStderrPublisher writes events to the standard error
+func NewStderrPublisher() *WriterPublisher {
+	return &WriterPublisher{writer: os.Stderr}
+}
+
+// NewFilePublisher appends events to the JSON lines file, the file is created when it does not exist
+func NewFilePublisher(path string) (*WriterPublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WriterPublisher{writer: file, closer: file}, nil
+}
+
+func (p *WriterPublisher) Publish(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	_, err = p.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (p *WriterPublisher) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+
+	return p.closer.Close()
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var emptyLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+
+type failingPublisher struct {
+	closed bool
+}
+
+func (p *failingPublisher) Publish(context.Context, Event) error {
+	return errors.New("broker is down")
+}
+
+func (p *failingPublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestFanoutPublishesToFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	file, err := NewFilePublisher(path)
+	require.NoError(t, err)
+	failing := &failingPublisher{}
+	fanout := NewFanout(emptyLogger, "ranked", 10, metrics.Noop{}).
+		Add(OutputFile, file).
+		Add("failing", failing)
+	sessions := make(chan matchmaking.MatchSession, 2)
+	added := matchmaking.NewMatchSession(matchmaking.ChangesTypeAdded, matchmaking.Player{ID: "1", Level: 1})
+	matched := matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "1", Level: 1})
+	sessions <- added
+	sessions <- matched
+	close(sessions)
+
+	// Act
+	err = fanout.Run(t.Context(), sessions)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, failing.closed)
+	written, err := os.Open(path)
+	require.NoError(t, err)
+	defer written.Close()
+	var events []Event
+	scanner := bufio.NewScanner(written)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, added.ID, events[0].ID)
+	assert.Equal(t, matched.ID, events[1].ID)
+	assert.Equal(t, "ranked", events[1].Queue)
+	assert.Equal(t, matched.Players, events[1].Players)
+}
+
+// blockedPublisher publishes once unblocked and keeps dropped events
+type blockedPublisher struct {
+	unblock chan struct{}
+	dropped []string
+}
+
+func (p *blockedPublisher) Publish(context.Context, Event) error {
+	<-p.unblock
+	return nil
+}
+
+func (p *blockedPublisher) Close() error {
+	return nil
+}
+
+func (p *blockedPublisher) Dropped(_ context.Context, event Event, _ error) {
+	p.dropped = append(p.dropped, event.ID)
+}
+
+func TestFanoutHandsDroppedEventsToPublisher(t *testing.T) {
+	// Arrange
+	blocked := &blockedPublisher{unblock: make(chan struct{})}
+	fanout := NewFanout(emptyLogger, "default", 10, metrics.Noop{}).AddSized("blocked", blocked, 1)
+	sessions := make(chan matchmaking.MatchSession)
+	matches := []matchmaking.MatchSession{
+		matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound),
+		matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound),
+		matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound),
+	}
+	done := make(chan error)
+	go func() { done <- fanout.Run(t.Context(), sessions) }()
+
+	// Act
+	for _, match := range matches {
+		sessions <- match
+	}
+	close(sessions)
+	close(blocked.unblock)
+
+	// Assert
+	require.NoError(t, <-done)
+	// the first event is published or waits in the queue with the second one, the third is dropped
+	assert.Contains(t, blocked.dropped, matches[2].ID)
+	assert.NotContains(t, blocked.dropped, matches[0].ID)
+}
+
+func TestConfiguredFanoutOutputs(t *testing.T) {
+	// Act
+	_, errUnknown := NewConfiguredFanout(emptyLogger, EventsConfig{EventsOutputs: []string{"kafka"}}, "default", metrics.Noop{})
+	fanout, errStd := NewConfiguredFanout(emptyLogger, EventsConfig{EventsOutputs: []string{OutputStdout, OutputStderr}}, "default", metrics.Noop{})
+
+	// Assert
+	assert.Error(t, errUnknown)
+	assert.NoError(t, errStd)
+	assert.False(t, fanout.Empty())
+}
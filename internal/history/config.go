@@ -0,0 +1,31 @@
+package history
+
+import "time"
+
+type HistoryConfig struct {
+	HistoryFile           string `env:"HISTORY_FILE"`
+	HistoryRetentionHours int    `env:"HISTORY_RETENTION_HOURS, default=720"`
+	HistoryMaxMatches     int    `env:"HISTORY_MAX_MATCHES, default=100000"`
+	HistoryPageSize       int    `env:"HISTORY_PAGE_SIZE, default=20"`
+	HistoryMaxPageSize    int    `env:"HISTORY_MAX_PAGE_SIZE, default=100"`
+	HistoryQueueSize      int    `env:"HISTORY_QUEUE_SIZE, default=1000"`
+}
+
+// Enabled matches are stored when the history file is configured
+func (c HistoryConfig) Enabled() bool {
+	return c.HistoryFile != ""
+}
+
+// Retention time matches are kept, zero keeps them regardless of age
+func (c HistoryConfig) Retention() time.Duration {
+	return time.Duration(c.HistoryRetentionHours) * time.Hour
+}
+
+// PageSize returns the size of a page for the requested size, the default one when it is not set
+func (c HistoryConfig) PageSize(requested int) int {
+	if requested <= 0 {
+		requested = c.HistoryPageSize
+	}
+
+	return min(max(requested, 1), max(c.HistoryMaxPageSize, 1))
+}
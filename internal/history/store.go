@@ -0,0 +1,326 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"time"
+)
+
+var (
+	// ErrMatchNotFound is returned when the match is not stored or has expired
+	ErrMatchNotFound = errors.New("match not found")
+	// ErrInvalidPageToken is returned for a page token not issued by the store
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+var (
+	// matchesBucket keeps matches by ID
+	matchesBucket = []byte("matches")
+	// createdBucket indexes match IDs by creation time, the oldest first, its sequence counts the matches
+	createdBucket = []byte("created")
+	// playersBucket indexes match IDs by player and creation time
+	playersBucket = []byte("players")
+)
+
+// Match is a found match as it is kept in the history
+type Match struct {
+	ID      string               `json:"id"`
+	Queue   string               `json:"queue"`
+	Created time.Time            `json:"created"`
+	Players []matchmaking.Player `json:"players"`
+	Quality float64              `json:"quality"`
+}
+
+// Store keeps found matches in an embedded database file, matches older than the retention
+// or above the maximum count are removed when new ones are saved
+type Store struct {
+	logger *slog.Logger
+	config HistoryConfig
+	queue  string
+	db     *bolt.DB
+}
+
+// Open opens or creates the history file, matches of the service carry the queue name
+func Open(logger *slog.Logger, config HistoryConfig, queue string) (*Store, error) {
+	db, err := bolt.Open(config.HistoryFile, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", config.HistoryFile, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{matchesBucket, createdBucket, playersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{logger: logger, config: config, queue: queue, db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Run saves found matches until the channel is closed, matches wait in a queue of HISTORY_QUEUE_SIZE
+// and are saved in batches, matches of a full queue are dropped
+func (s *Store) Run(ctx context.Context, sessions <-chan matchmaking.MatchSession) error {
+	queue := make(chan Match, max(s.config.HistoryQueueSize, 1))
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		for match := range queue {
+			batch := []Match{match}
+			for len(batch) < cap(queue) {
+				next, ok := s.dequeue(queue)
+				if !ok {
+					break
+				}
+				batch = append(batch, next)
+			}
+			if err := s.save(batch, time.Now()); err != nil {
+				s.logger.ErrorContext(ctx, "failed to save matches", slog.Int("matches", len(batch)), slog.String("error", err.Error()))
+			}
+		}
+	}()
+
+	for session := range sessions {
+		if session.Type != matchmaking.ChangesTypeMatchFound || len(session.Players) == 0 {
+			continue
+		}
+
+		match := Match{
+			ID:      session.ID,
+			Queue:   s.queue,
+			Created: session.Created,
+			Players: session.Players,
+			Quality: session.Quality,
+		}
+		select {
+		case queue <- match:
+		default:
+			s.logger.WarnContext(ctx, "match not saved, history queue is full", slog.String("match_id", match.ID))
+		}
+	}
+	close(queue)
+	<-saved
+
+	return nil
+}
+
+// dequeue returns a queued match without waiting for one
+func (s *Store) dequeue(queue <-chan Match) (Match, bool) {
+	select {
+	case match, ok := <-queue:
+		return match, ok
+	default:
+		return Match{}, false
+	}
+}
+
+// Save stores the match and removes matches beyond the retention limits at the time
+func (s *Store) Save(match Match, now time.Time) error {
+	return s.save([]Match{match}, now)
+}
+
+// save stores matches in a single transaction and removes matches beyond the retention limits at the time
+func (s *Store) save(batch []Match, now time.Time) error {
+	values := make([][]byte, 0, len(batch))
+	for _, match := range batch {
+		data, err := json.Marshal(match)
+		if err != nil {
+			return err
+		}
+		values = append(values, data)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for i, match := range batch {
+			if err := s.put(tx, match, values[i]); err != nil {
+				return err
+			}
+		}
+
+		return s.prune(tx, now)
+	})
+}
+
+// put stores the match and its indexes unless it is already stored
+func (s *Store) put(tx *bolt.Tx, match Match, data []byte) error {
+	matches := tx.Bucket(matchesBucket)
+	if matches.Get([]byte(match.ID)) != nil {
+		return nil
+	}
+	if err := matches.Put([]byte(match.ID), data); err != nil {
+		return err
+	}
+	created := tx.Bucket(createdBucket)
+	if err := created.Put(timeKey(nil, match.Created, match.ID), nil); err != nil {
+		return err
+	}
+	if err := created.SetSequence(created.Sequence() + 1); err != nil {
+		return err
+	}
+	for _, p := range match.Players {
+		if err := tx.Bucket(playersBucket).Put(playerKey(p.ID, match.Created, match.ID), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns the match by ID, matches beyond the retention are not found even before they are removed
+func (s *Store) Get(id string) (Match, error) {
+	var match Match
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		match, err = getMatch(tx, id)
+		if err == nil && s.expired(match.Created, time.Now()) {
+			return fmt.Errorf("%w: %s", ErrMatchNotFound, id)
+		}
+		return err
+	})
+
+	return match, err
+}
+
+// ListPlayerMatches returns a page of matches of the player, the newest first, and the token of the next page,
+// the token is empty on the last page, matches beyond the retention are left out
+func (s *Store) ListPlayerMatches(playerID string, pageSize int, pageToken string) ([]Match, string, error) {
+	prefix := playerKey(playerID, time.Time{}, "")[:len(playerID)+1]
+	var start []byte
+	if pageToken != "" {
+		var err error
+		start, err = base64.RawURLEncoding.DecodeString(pageToken)
+		if err != nil || !bytes.HasPrefix(start, prefix) {
+			return nil, "", ErrInvalidPageToken
+		}
+	}
+	pageSize = s.config.PageSize(pageSize)
+
+	var matches []Match
+	var nextToken string
+	now := time.Now()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(playersBucket).Cursor()
+		key := s.seekBefore(cursor, prefix, start)
+		for ; key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Prev() {
+			// keys before an expired one are older, so expired as well
+			if s.expired(keyTime(key[len(prefix):]), now) {
+				return nil
+			}
+			if len(matches) == pageSize {
+				nextToken = base64.RawURLEncoding.EncodeToString(key)
+				return nil
+			}
+
+			match, err := getMatch(tx, string(key[len(prefix)+8:]))
+			if err != nil {
+				return err
+			}
+			matches = append(matches, match)
+		}
+		return nil
+	})
+
+	return matches, nextToken, err
+}
+
+// seekBefore positions the cursor at the start key of the page, or the key before it when the match has expired,
+// without the start key at the newest key of the prefix
+func (s *Store) seekBefore(cursor *bolt.Cursor, prefix, start []byte) []byte {
+	if start == nil {
+		// keys of the prefix are followed by the first key of the next player, if any
+		start = append(bytes.Clone(prefix[:len(prefix)-1]), prefix[len(prefix)-1]+1)
+	}
+
+	key, _ := cursor.Seek(start)
+	switch {
+	case key == nil:
+		key, _ = cursor.Last()
+	case !bytes.Equal(key, start):
+		key, _ = cursor.Prev()
+	}
+	return key
+}
+
+// prune removes the oldest matches while they exceed the maximum count or the retention
+func (s *Store) prune(tx *bolt.Tx, now time.Time) error {
+	created := tx.Bucket(createdBucket)
+	count := created.Sequence()
+	cursor := created.Cursor()
+	for key, _ := cursor.First(); key != nil; key, _ = cursor.First() {
+		expired := s.expired(keyTime(key), now)
+		overflow := s.config.HistoryMaxMatches > 0 && count > uint64(s.config.HistoryMaxMatches)
+		if !expired && !overflow {
+			return nil
+		}
+
+		match, err := getMatch(tx, string(key[8:]))
+		if err != nil && !errors.Is(err, ErrMatchNotFound) {
+			return err
+		}
+		for _, p := range match.Players {
+			if err := tx.Bucket(playersBucket).Delete(playerKey(p.ID, match.Created, match.ID)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(matchesBucket).Delete(key[8:]); err != nil {
+			return err
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+		count--
+		if err := created.SetSequence(count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expired reports whether the match created at the time is beyond the retention
+func (s *Store) expired(created, now time.Time) bool {
+	return s.config.Retention() > 0 && now.Sub(created) > s.config.Retention()
+}
+
+func getMatch(tx *bolt.Tx, id string) (Match, error) {
+	data := tx.Bucket(matchesBucket).Get([]byte(id))
+	if data == nil {
+		return Match{}, fmt.Errorf("%w: %s", ErrMatchNotFound, id)
+	}
+
+	var match Match
+	err := json.Unmarshal(data, &match)
+	return match, err
+}
+
+// timeKey appends the big endian creation time and the match ID, so keys are sorted by time
+func timeKey(prefix []byte, created time.Time, matchID string) []byte {
+	key := binary.BigEndian.AppendUint64(prefix, uint64(created.UnixNano()))
+	return append(key, matchID...)
+}
+
+// playerKey is the player ID, a zero separator, then the time key of the match
+func playerKey(playerID string, created time.Time, matchID string) []byte {
+	return timeKey(append([]byte(playerID), 0), created, matchID)
+}
+
+func keyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
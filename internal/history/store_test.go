@@ -0,0 +1,153 @@
+package history
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var emptyLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+
+func openTestStore(t *testing.T, config HistoryConfig) *Store {
+	config.HistoryFile = filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(emptyLogger, config, "default")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func testMatch(id string, created time.Time, playerIDs ...string) Match {
+	players := make([]matchmaking.Player, 0, len(playerIDs))
+	for i, playerID := range playerIDs {
+		players = append(players, matchmaking.Player{ID: playerID, Level: i})
+	}
+
+	return Match{ID: id, Queue: "default", Created: created, Players: players, Quality: 0.5}
+}
+
+func TestStoreGetMatch(t *testing.T) {
+	// Arrange
+	store := openTestStore(t, HistoryConfig{})
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	sessions := make(chan matchmaking.MatchSession, 2)
+	matched := matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "1", Level: 3})
+	matched.Created = created
+	matched.Quality = 0.75
+	timeout := matchmaking.NewMatchSession(matchmaking.ChangesTypeTimeout, matchmaking.Player{ID: "2", Level: 1})
+	sessions <- matched
+	sessions <- timeout
+	close(sessions)
+
+	// Act
+	require.NoError(t, store.Run(t.Context(), sessions))
+	match, err := store.Get(matched.ID)
+	_, notFoundErr := store.Get(timeout.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, Match{ID: matched.ID, Queue: "default", Created: created, Players: matched.Players, Quality: 0.75}, match)
+	assert.ErrorIs(t, notFoundErr, ErrMatchNotFound)
+}
+
+func TestStoreListPlayerMatchesPages(t *testing.T) {
+	// Arrange
+	store := openTestStore(t, HistoryConfig{HistoryPageSize: 2, HistoryMaxPageSize: 10})
+	now := time.Now()
+	require.NoError(t, store.Save(testMatch("m1", now.Add(-3*time.Minute), "a", "b"), now))
+	require.NoError(t, store.Save(testMatch("m2", now.Add(-2*time.Minute), "a", "c"), now))
+	require.NoError(t, store.Save(testMatch("m3", now.Add(-time.Minute), "b", "c"), now))
+	require.NoError(t, store.Save(testMatch("m4", now, "a", "ab"), now))
+
+	// Act
+	first, token, err := store.ListPlayerMatches("a", 0, "")
+	require.NoError(t, err)
+	second, lastToken, err := store.ListPlayerMatches("a", 0, token)
+	require.NoError(t, err)
+	other, _, err := store.ListPlayerMatches("ab", 0, "")
+	require.NoError(t, err)
+	_, _, invalidErr := store.ListPlayerMatches("b", 0, token)
+
+	// Assert
+	assert.Equal(t, []string{"m4", "m2"}, matchIDs(first))
+	assert.NotEmpty(t, token)
+	assert.Equal(t, []string{"m1"}, matchIDs(second))
+	assert.Empty(t, lastToken)
+	assert.Equal(t, []string{"m4"}, matchIDs(other))
+	assert.ErrorIs(t, invalidErr, ErrInvalidPageToken)
+}
+
+func TestStorePrunes(t *testing.T) {
+	// Arrange
+	store := openTestStore(t, HistoryConfig{HistoryRetentionHours: 1, HistoryMaxMatches: 2, HistoryPageSize: 10, HistoryMaxPageSize: 10})
+	now := time.Now()
+
+	// Act
+	require.NoError(t, store.Save(testMatch("expired", now.Add(-2*time.Hour), "a"), now.Add(-2*time.Hour)))
+	require.NoError(t, store.Save(testMatch("oldest", now.Add(-3*time.Minute), "a"), now))
+	require.NoError(t, store.Save(testMatch("older", now.Add(-2*time.Minute), "a"), now))
+	require.NoError(t, store.Save(testMatch("newest", now.Add(-time.Minute), "a"), now))
+	matches, _, err := store.ListPlayerMatches("a", 0, "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"newest", "older"}, matchIDs(matches))
+	_, expiredErr := store.Get("expired")
+	assert.ErrorIs(t, expiredErr, ErrMatchNotFound)
+}
+
+func TestStoreHidesExpiredMatches(t *testing.T) {
+	// Arrange
+	store := openTestStore(t, HistoryConfig{HistoryRetentionHours: 1, HistoryPageSize: 10, HistoryMaxPageSize: 10})
+	now := time.Now()
+	require.NoError(t, store.Save(testMatch("fresh", now.Add(-time.Minute), "a"), now))
+	// saved before it expired, so it is not pruned yet
+	require.NoError(t, store.Save(testMatch("expired", now.Add(-2*time.Hour), "a"), now.Add(-2*time.Hour)))
+
+	// Act
+	matches, token, err := store.ListPlayerMatches("a", 0, "")
+	_, expiredErr := store.Get("expired")
+	_, freshErr := store.Get("fresh")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fresh"}, matchIDs(matches))
+	assert.Empty(t, token)
+	assert.ErrorIs(t, expiredErr, ErrMatchNotFound)
+	assert.NoError(t, freshErr)
+}
+
+func TestStoreRunSavesQueuedMatches(t *testing.T) {
+	// Arrange
+	store := openTestStore(t, HistoryConfig{HistoryQueueSize: 10, HistoryPageSize: 10, HistoryMaxPageSize: 10})
+	sessions := make(chan matchmaking.MatchSession, 5)
+	ids := make([]string, 0, 5)
+	for i := range 5 {
+		session := matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "a", Level: i})
+		session.Created = time.Now().Add(time.Duration(i-5) * time.Minute)
+		sessions <- session
+		ids = append([]string{session.ID}, ids...)
+	}
+	close(sessions)
+
+	// Act
+	require.NoError(t, store.Run(t.Context(), sessions))
+	matches, _, err := store.ListPlayerMatches("a", 0, "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ids, matchIDs(matches))
+}
+
+func matchIDs(matches []Match) []string {
+	ids := make([]string, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	return ids
+}
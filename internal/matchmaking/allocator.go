@@ -0,0 +1,206 @@
+package matchmaking
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrAllocationFailed is returned when no game server is allocated for a match
+var ErrAllocationFailed = errors.New("game server allocation failed")
+
+// Endpoint is the game server players of a match connect to
+type Endpoint struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Token admits players of the match to the game server
+	Token string `json:"token"`
+}
+
+// Allocator allocates a game server for every found match and releases it when the match is not created
+type Allocator interface {
+	Allocate(ctx context.Context, matchID string, players []Player) (Endpoint, error)
+	Release(ctx context.Context, matchID string, endpoint Endpoint) error
+}
+
+// AllocatorFunc adapts a function to Allocator, it has nothing to release
+type AllocatorFunc func(ctx context.Context, matchID string, players []Player) (Endpoint, error)
+
+func (f AllocatorFunc) Allocate(ctx context.Context, matchID string, players []Player) (Endpoint, error) {
+	return f(ctx, matchID, players)
+}
+
+func (f AllocatorFunc) Release(context.Context, string, Endpoint) error {
+	return nil
+}
+
+// StaticAllocator hands out game servers of a static pool in turn with a new token for every match,
+// it does not track running games and is meant for tests and local development
+type StaticAllocator struct {
+	pool []Endpoint
+	next atomic.Uint64
+}
+
+var _ Allocator = (*StaticAllocator)(nil)
+
+// NewStaticAllocator creates an allocator of game servers with host:port addresses
+func NewStaticAllocator(addresses []string) (*StaticAllocator, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no game server addresses provided")
+	}
+
+	pool := make([]Endpoint, 0, len(addresses))
+	for _, address := range addresses {
+		host, portValue, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid game server address %q: %w", address, err)
+		}
+		port, err := strconv.Atoi(portValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid game server port %q: %w", address, err)
+		}
+		pool = append(pool, Endpoint{Host: host, Port: port})
+	}
+
+	return &StaticAllocator{pool: pool}, nil
+}
+
+func (a *StaticAllocator) Allocate(ctx context.Context, _ string, _ []Player) (Endpoint, error) {
+	if err := ctx.Err(); err != nil {
+		return Endpoint{}, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return Endpoint{}, err
+	}
+
+	endpoint := a.pool[(a.next.Add(1)-1)%uint64(len(a.pool))]
+	endpoint.Token = hex.EncodeToString(token)
+	return endpoint, nil
+}
+
+// Release does nothing, the pool is handed out in turn regardless of running games
+func (a *StaticAllocator) Release(context.Context, string, Endpoint) error {
+	return nil
+}
+
+// SetAllocator allocates a game server for every match before it is created, call it before Start.
+// Without an allocator matches have no endpoint.
+func (m *Service) SetAllocator(allocator Allocator) *Service {
+	m.allocator = allocator
+	return m
+}
+
+// allocate allocates a game server for the players in the background and queues their match,
+// the players are not grouped again until the match is handled. Returns false when all allocation slots are busy,
+// the players stay in the queue for the next tick then.
+func (m *Service) allocate(ctx context.Context, players []Player) bool {
+	select {
+	case m.allocationSlots <- struct{}{}:
+	default:
+		return false
+	}
+
+	m.reserve(players)
+	m.allocations.Add(1)
+	go func() {
+		defer m.allocations.Done()
+		defer func() { <-m.allocationSlots }()
+
+		matchCtx, matchSpan := startMatchSpan(ctx, players)
+		defer matchSpan.End()
+		qc, err := m.newMatchCommand(matchCtx, players...)
+		if err != nil {
+			m.unreserve(players)
+			return
+		}
+		if err := m.enqueue(matchCtx, qc); err != nil {
+			m.unreserve(players)
+			m.release(qc)
+		}
+	}()
+
+	return true
+}
+
+// reserve keeps the players out of new groups while their game server is allocated
+func (m *Service) reserve(players []Player) {
+	m.reservedL.Lock()
+	defer m.reservedL.Unlock()
+
+	for _, p := range players {
+		m.reserved[p.ID] = true
+	}
+}
+
+func (m *Service) unreserve(players []Player) {
+	m.reservedL.Lock()
+	defer m.reservedL.Unlock()
+
+	for _, p := range players {
+		delete(m.reserved, p.ID)
+	}
+}
+
+// unreserved returns the players which are not waiting for a game server
+func (m *Service) unreserved(players []StoredPlayer) []StoredPlayer {
+	m.reservedL.Lock()
+	defer m.reservedL.Unlock()
+
+	if len(m.reserved) == 0 {
+		return players
+	}
+
+	return slices.DeleteFunc(players, func(p StoredPlayer) bool {
+		return m.reserved[p.ID]
+	})
+}
+
+// release gives back the game server of a match which is not created, in the background
+// so the command loop is not blocked by the allocator
+func (m *Service) release(qc queueCommand) {
+	if m.allocator == nil || qc.endpoint == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.config.AllocationTimeout())
+		defer cancel()
+		if err := m.allocator.Release(ctx, qc.matchID, *qc.endpoint); err != nil {
+			m.logger.WarnContext(ctx, "Game server release failed", slog.String("match_id", qc.matchID), slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// newMatchCommand allocates a game server for the players and returns the command creating their match,
+// the players stay in the queue when the allocation fails
+func (m *Service) newMatchCommand(ctx context.Context, players ...Player) (queueCommand, error) {
+	qc := newQueueCommand(ctx, createMatchCommand, players...)
+	qc.matchID = uuid.NewString()
+	if m.allocator == nil {
+		return qc, nil
+	}
+
+	allocateCtx, cancel := context.WithTimeout(ctx, m.config.AllocationTimeout())
+	defer cancel()
+	endpoint, err := m.allocator.Allocate(allocateCtx, qc.matchID, players)
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		m.metrics.AllocationFailed()
+		m.logger.WarnContext(ctx, "Game server allocation failed", slog.String("match_id", qc.matchID), slog.String("error", err.Error()))
+		return qc, fmt.Errorf("%w: %w", ErrAllocationFailed, err)
+	}
+	qc.endpoint = &endpoint
+
+	return qc, nil
+}
@@ -0,0 +1,233 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"matchmaking/internal/metrics"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestStaticAllocatorInTurn(t *testing.T) {
+	// Arrange
+	allocator, err := NewStaticAllocator([]string{"10.0.0.1:7777", "10.0.0.2:7778"})
+	require.NoError(t, err)
+
+	// Act
+	first, _ := allocator.Allocate(t.Context(), "1", nil)
+	second, _ := allocator.Allocate(t.Context(), "2", nil)
+	third, _ := allocator.Allocate(t.Context(), "3", nil)
+
+	// Assert
+	assert.Equal(t, "10.0.0.1", first.Host)
+	assert.Equal(t, 7777, first.Port)
+	assert.Equal(t, "10.0.0.2", second.Host)
+	assert.Equal(t, 7778, second.Port)
+	assert.Equal(t, "10.0.0.1", third.Host)
+	assert.NotEmpty(t, first.Token)
+	assert.NotEqual(t, first.Token, third.Token)
+}
+
+func TestStaticAllocatorInvalidAddress(t *testing.T) {
+	// Act
+	_, noAddressesErr := NewStaticAllocator(nil)
+	_, noPortErr := NewStaticAllocator([]string{"10.0.0.1"})
+	_, invalidPortErr := NewStaticAllocator([]string{"10.0.0.1:port"})
+
+	// Assert
+	assert.Error(t, noAddressesErr)
+	assert.Error(t, noPortErr)
+	assert.Error(t, invalidPortErr)
+}
+
+func TestMatchSessionAllocated(t *testing.T) {
+	// Arrange
+	var allocatedID string
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MaxLevelDiff:             10,
+		MatchTimeoutAfterSeconds: 60,
+	}, NewStorage(), metrics.Noop{}).SetAllocator(AllocatorFunc(func(_ context.Context, matchID string, _ []Player) (Endpoint, error) {
+		allocatedID = matchID
+		return Endpoint{Host: "game", Port: 7777, Token: "token"}, nil
+	}))
+
+	// Act
+	var match MatchSession
+	synctest.Run(func() {
+		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
+		defer cancelFunc()
+		output, _ := service.Start(ctx)
+		_ = service.AddPlayer(Player{ID: "1", Level: 1}, Player{ID: "2", Level: 2})
+
+		for session := range output {
+			if session.Type == ChangesTypeMatchFound {
+				match = session
+				cancelFunc()
+			}
+		}
+	})
+
+	// Assert
+	require.NotNil(t, match.Endpoint)
+	assert.Equal(t, Endpoint{Host: "game", Port: 7777, Token: "token"}, *match.Endpoint)
+	assert.Equal(t, allocatedID, match.ID)
+}
+
+func TestMatchSessionAllocationFailed(t *testing.T) {
+	// Arrange
+	attempts := atomic.Int32{}
+	storage := NewStorage()
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MaxLevelDiff:             10,
+		MatchTimeoutAfterSeconds: 60,
+	}, storage, metrics.Noop{}).SetAllocator(AllocatorFunc(func(context.Context, string, []Player) (Endpoint, error) {
+		if attempts.Add(1) == 1 {
+			return Endpoint{}, errors.New("no game servers")
+		}
+		return Endpoint{Host: "game", Port: 7777}, nil
+	}))
+
+	// Act
+	var playersAfterFailure int
+	var match MatchSession
+	synctest.Run(func() {
+		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
+		defer cancelFunc()
+		output, _ := service.Start(ctx)
+		_ = service.AddPlayer(Player{ID: "1", Level: 1}, Player{ID: "2", Level: 2})
+
+		time.Sleep(time.Millisecond * 1500)
+		synctest.Wait()
+		playersAfterFailure = storage.TotalPlayers()
+
+		for session := range output {
+			if session.Type == ChangesTypeMatchFound {
+				match = session
+				cancelFunc()
+			}
+		}
+	})
+
+	// Assert
+	assert.Equal(t, 2, playersAfterFailure)
+	assert.EqualValues(t, 2, attempts.Load())
+	require.NotNil(t, match.Endpoint)
+	assert.Len(t, match.Players, 2)
+}
+
+// blockingAllocator allocates once unblocked and records released matches
+type blockingAllocator struct {
+	unblock  chan struct{}
+	attempts atomic.Int32
+	released chan string
+}
+
+func (a *blockingAllocator) Allocate(ctx context.Context, matchID string, players []Player) (Endpoint, error) {
+	a.attempts.Add(1)
+	if players[0].Level < 100 {
+		select {
+		case <-ctx.Done():
+			return Endpoint{}, ctx.Err()
+		case <-a.unblock:
+		}
+	}
+	return Endpoint{Host: "game", Port: 7777}, nil
+}
+
+func (a *blockingAllocator) Release(_ context.Context, matchID string, _ Endpoint) error {
+	a.released <- matchID
+	return nil
+}
+
+func newAllocationTestService(storage *Storage, allocator Allocator) *Service {
+	return NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MaxLevelDiff:             10,
+		MatchTimeoutAfterSeconds: 60,
+		AllocationTimeoutSeconds: 30,
+		AllocationConcurrency:    2,
+	}, storage, metrics.Noop{}).SetAllocator(allocator)
+}
+
+func TestAllocationDoesNotBlockMatching(t *testing.T) {
+	// Act
+	var matches []MatchSession
+	var attempts int32
+	synctest.Run(func() {
+		// channels of the allocator belong to the bubble, so time advances while it blocks
+		allocator := &blockingAllocator{unblock: make(chan struct{}), released: make(chan string, 1)}
+		service := newAllocationTestService(NewStorage(), allocator)
+		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*10)
+		defer cancelFunc()
+		output, _ := service.Start(ctx)
+		_ = service.AddPlayer(Player{ID: "1", Level: 1}, Player{ID: "2", Level: 2}, Player{ID: "3", Level: 100}, Player{ID: "4", Level: 101})
+
+		for session := range output {
+			if session.Type != ChangesTypeMatchFound {
+				continue
+			}
+			matches = append(matches, session)
+			if len(matches) == 1 {
+				// the blocked allocation is not started again on next ticks
+				time.Sleep(3 * time.Second)
+				close(allocator.unblock)
+			} else {
+				cancelFunc()
+			}
+		}
+		attempts = allocator.attempts.Load()
+	})
+
+	// Assert
+	require.Len(t, matches, 2)
+	assert.Equal(t, []Player{{ID: "3", Level: 100}, {ID: "4", Level: 101}}, matches[0].Players)
+	assert.Equal(t, []Player{{ID: "1", Level: 1}, {ID: "2", Level: 2}}, matches[1].Players)
+	assert.EqualValues(t, 2, attempts)
+}
+
+func TestMatchNotCreatedWhenPlayerLeftDuringAllocation(t *testing.T) {
+	// Act
+	var released string
+	var types []PlayerChangesType
+	var waiting []StoredPlayer
+	synctest.Run(func() {
+		allocator := &blockingAllocator{unblock: make(chan struct{}), released: make(chan string, 1)}
+		storage := NewStorage()
+		service := newAllocationTestService(storage, allocator)
+		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*10)
+		defer cancelFunc()
+		output, _ := service.Start(ctx)
+		_ = service.AddPlayer(Player{ID: "1", Level: 1}, Player{ID: "2", Level: 2})
+
+		time.Sleep(1500 * time.Millisecond)
+		_ = service.RemovePlayer(Player{ID: "1"})
+		synctest.Wait()
+		close(allocator.unblock)
+		released = <-allocator.released
+		synctest.Wait()
+		waiting = storage.GetSortedByLevelPlayers()
+		cancelFunc()
+
+		for session := range output {
+			types = append(types, session.Type)
+		}
+	})
+
+	// Assert
+	assert.NotEmpty(t, released)
+	assert.NotContains(t, types, ChangesTypeMatchFound)
+	require.Len(t, waiting, 1)
+	assert.Equal(t, "2", waiting[0].ID)
+}
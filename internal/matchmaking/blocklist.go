@@ -0,0 +1,156 @@
+package matchmaking
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBlockListFull is returned when a player blocks more players than BLOCK_LIST_SIZE
+	ErrBlockListFull = errors.New("block list is full")
+	// ErrAvoidListTooLong is returned when a player avoids more players than BLOCK_LIST_SIZE
+	ErrAvoidListTooLong = errors.New("avoid list is too long")
+)
+
+// BlockList keeps the players each player refuses to be matched with, a block works both ways
+type BlockList struct {
+	l       sync.RWMutex
+	size    int
+	blocked map[string]map[string]struct{}
+}
+
+// NewBlockList creates a block list of up to size blocked players per player, zero does not limit it
+func NewBlockList(size int) *BlockList {
+	return &BlockList{
+		size:    size,
+		blocked: make(map[string]map[string]struct{}),
+	}
+}
+
+// Block adds players to the block list of the player and returns the list, the player itself is ignored
+func (b *BlockList) Block(playerID string, blocked ...string) ([]string, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	list := b.blocked[playerID]
+	added := make(map[string]struct{})
+	for _, id := range blocked {
+		if _, ok := list[id]; !ok && id != "" && id != playerID {
+			added[id] = struct{}{}
+		}
+	}
+	if b.size > 0 && len(list)+len(added) > b.size {
+		return nil, fmt.Errorf("%w: %d players at most", ErrBlockListFull, b.size)
+	}
+	if len(added) == 0 {
+		return sortedIDs(list), nil
+	}
+
+	if list == nil {
+		list = make(map[string]struct{}, len(added))
+		b.blocked[playerID] = list
+	}
+	maps.Copy(list, added)
+	return sortedIDs(list), nil
+}
+
+// Unblock removes players from the block list of the player and returns the list
+func (b *BlockList) Unblock(playerID string, blocked ...string) []string {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	list := b.blocked[playerID]
+	for _, id := range blocked {
+		delete(list, id)
+	}
+	if len(list) == 0 {
+		delete(b.blocked, playerID)
+	}
+
+	return sortedIDs(list)
+}
+
+// Get returns the block list of the player
+func (b *BlockList) Get(playerID string) []string {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	return sortedIDs(b.blocked[playerID])
+}
+
+// Blocked reports whether either player has blocked the other one
+func (b *BlockList) Blocked(first, second string) bool {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	_, blocked := b.blocked[first][second]
+	if !blocked {
+		_, blocked = b.blocked[second][first]
+	}
+	return blocked
+}
+
+// all returns block lists of all players
+func (b *BlockList) all() map[string][]string {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	lists := make(map[string][]string, len(b.blocked))
+	for playerID, list := range b.blocked {
+		lists[playerID] = sortedIDs(list)
+	}
+	return lists
+}
+
+// restore adds saved block lists, lists above the size are kept as they were saved
+func (b *BlockList) restore(lists map[string][]string) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	for playerID, blocked := range lists {
+		for _, id := range blocked {
+			if id == "" || id == playerID {
+				continue
+			}
+			if b.blocked[playerID] == nil {
+				b.blocked[playerID] = make(map[string]struct{}, len(blocked))
+			}
+			b.blocked[playerID][id] = struct{}{}
+		}
+	}
+}
+
+func sortedIDs(set map[string]struct{}) []string {
+	return slices.Sorted(maps.Keys(set))
+}
+
+// BlockList returns the block list consulted by the matcher
+func (m *Service) BlockList() *BlockList {
+	return m.blocks
+}
+
+// blocked reports whether players avoid each other in their tickets or block lists
+func (m *Service) blocked(a, b Player) bool {
+	return slices.Contains(a.Avoid, b.ID) || slices.Contains(b.Avoid, a.ID) || m.blocks.Blocked(a.ID, b.ID)
+}
+
+// blockFallback reports whether blocked players waited long enough to be matched anyway
+func (m *Service) blockFallback(a, b StoredPlayer, now time.Time) bool {
+	fallback := m.config.BlockFallback()
+	return fallback > 0 && now.Sub(a.Created) > fallback && now.Sub(b.Created) > fallback
+}
+
+// validateAvoid checks avoid lists of the players against BLOCK_LIST_SIZE
+func (m *Service) validateAvoid(players []Player) error {
+	for _, p := range players {
+		if m.config.BlockListSize > 0 && len(p.Avoid) > m.config.BlockListSize {
+			return fmt.Errorf("%w: player %s avoids %d players, %d at most", ErrAvoidListTooLong, p.ID, len(p.Avoid), m.config.BlockListSize)
+		}
+	}
+
+	return nil
+}
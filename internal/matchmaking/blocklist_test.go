@@ -0,0 +1,157 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"matchmaking/internal/metrics"
+	"testing"
+	"time"
+)
+
+func TestBlockList(t *testing.T) {
+	// Arrange
+	blocks := NewBlockList(2)
+
+	// Act
+	blocked, err := blocks.Block("1", "2", "1", "2")
+	require.NoError(t, err)
+	_, errFull := blocks.Block("1", "3", "4")
+	unblocked := blocks.Unblock("1", "2")
+
+	// Assert
+	assert.Equal(t, []string{"2"}, blocked)
+	assert.ErrorIs(t, errFull, ErrBlockListFull)
+	assert.Empty(t, unblocked)
+	assert.Empty(t, blocks.blocked)
+}
+
+func TestBlockListBothWays(t *testing.T) {
+	// Arrange
+	blocks := NewBlockList(0)
+
+	// Act
+	_, err := blocks.Block("1", "2")
+	require.NoError(t, err)
+
+	// Assert
+	assert.True(t, blocks.Blocked("1", "2"))
+	assert.True(t, blocks.Blocked("2", "1"))
+	assert.False(t, blocks.Blocked("1", "3"))
+	assert.Equal(t, []string{"2"}, blocks.Get("1"))
+	assert.Empty(t, blocks.Get("2"))
+}
+
+func TestFindMatchBlockedPlayers(t *testing.T) {
+	now := time.Now()
+	longAgo := now.Add(-time.Minute)
+	tests := []struct {
+		name     string
+		players  []StoredPlayer
+		blocks   map[string]string
+		fallback int
+		expected []string
+	}{
+		{
+			name: "avoided in ticket",
+			players: []StoredPlayer{
+				{Player: Player{ID: "1", Level: 5, Avoid: []string{"2"}}, Created: now},
+				{Player: Player{ID: "2", Level: 5}, Created: now},
+				{Player: Player{ID: "3", Level: 6}, Created: now},
+			},
+			expected: []string{"1", "3"},
+		},
+		{
+			name: "avoided by candidate",
+			players: []StoredPlayer{
+				{Player: Player{ID: "1", Level: 5}, Created: now},
+				{Player: Player{ID: "2", Level: 5, Avoid: []string{"1"}}, Created: now},
+				{Player: Player{ID: "3", Level: 6}, Created: now},
+			},
+			expected: []string{"1", "3"},
+		},
+		{
+			name: "blocked in block list",
+			players: []StoredPlayer{
+				{Player: Player{ID: "1", Level: 5}, Created: now},
+				{Player: Player{ID: "2", Level: 5}, Created: now},
+				{Player: Player{ID: "3", Level: 6}, Created: now},
+			},
+			blocks:   map[string]string{"2": "1"},
+			expected: []string{"1", "3"},
+		},
+		{
+			name: "no other players",
+			players: []StoredPlayer{
+				{Player: Player{ID: "1", Level: 5}, Created: longAgo},
+				{Player: Player{ID: "2", Level: 5}, Created: longAgo},
+			},
+			blocks: map[string]string{"1": "2"},
+		},
+		{
+			name: "fallback after long wait",
+			players: []StoredPlayer{
+				{Player: Player{ID: "1", Level: 5}, Created: longAgo},
+				{Player: Player{ID: "2", Level: 5}, Created: longAgo},
+			},
+			blocks:   map[string]string{"1": "2"},
+			fallback: 30,
+			expected: []string{"1", "2"},
+		},
+		{
+			name: "fallback waits for both players",
+			players: []StoredPlayer{
+				{Player: Player{ID: "1", Level: 5}, Created: longAgo},
+				{Player: Player{ID: "2", Level: 5}, Created: now},
+			},
+			blocks:   map[string]string{"1": "2"},
+			fallback: 30,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			service := NewService(emptyLogger, MatchmakingConfig{
+				MinGroupSize:         2,
+				MaxLevelDiff:         5,
+				BlockFallbackSeconds: tt.fallback,
+			}, NewStorage(), metrics.Noop{})
+			for player, blocked := range tt.blocks {
+				_, err := service.BlockList().Block(player, blocked)
+				require.NoError(t, err)
+			}
+
+			// Act
+			match := service.findMatch(tt.players, tt.players[0], nil, now)
+
+			// Assert
+			var ids []string
+			for _, p := range match {
+				ids = append(ids, p.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestAvoidListsArePrivate(t *testing.T) {
+	// Arrange
+	player := Player{ID: "1", Level: 5, Avoid: []string{"2"}}
+
+	// Act
+	session := NewMatchSession(ChangesTypeMatchFound, player)
+
+	// Assert
+	assert.Equal(t, []Player{{ID: "1", Level: 5}}, session.Players)
+	assert.Equal(t, []string{"2"}, player.Avoid)
+}
+
+func TestAddPlayerAvoidListTooLong(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{QueueSize: 10, BlockListSize: 1}, NewStorage(), metrics.Noop{})
+
+	// Act
+	err := service.AddPlayer(Player{ID: "1", Avoid: []string{"2", "3"}})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrAvoidListTooLong)
+}
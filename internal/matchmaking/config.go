@@ -1,13 +1,35 @@
 package matchmaking
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type MatchmakingConfig struct {
-	QueueSize                int `env:"QUEUE_SIZE, default=25"`
-	MinGroupSize             int `env:"MIN_GROUP_SIZE, default=10"`
-	MaxLevelDiff             int `env:"MAX_LEVEL_DIFF, default=10"`
-	FindGroupEverySeconds    int `env:"FIND_GROUP_EVERY_SECONDS, default=1"`
-	MatchTimeoutAfterSeconds int `env:"MATCH_TIMEOUT_AFTER_SECONDS, default=60"`
+	QueueName                string   `env:"QUEUE_NAME, default=default"`
+	QueueSize                int      `env:"QUEUE_SIZE, default=25"`
+	MinGroupSize             int      `env:"MIN_GROUP_SIZE, default=10"`
+	MaxLevelDiff             int      `env:"MAX_LEVEL_DIFF, default=10"`
+	FindGroupEverySeconds    int      `env:"FIND_GROUP_EVERY_SECONDS, default=1"`
+	MatchTimeoutAfterSeconds int      `env:"MATCH_TIMEOUT_AFTER_SECONDS, default=60"`
+	LevelBandSize            int      `env:"LEVEL_BAND_SIZE, default=10"`
+	SessionHistorySize       int      `env:"SESSION_HISTORY_SIZE, default=100"`
+	SnapshotFile             string   `env:"SNAPSHOT_FILE"`
+	HealthStallSeconds       int      `env:"HEALTH_STALL_SECONDS, default=10"`
+	RestartMax               int      `env:"RESTART_MAX, default=5"`
+	RestartBackoffMillis     int      `env:"RESTART_BACKOFF_MILLISECONDS, default=500"`
+	AllocatorAddresses       []string `env:"ALLOCATOR_ADDRESSES"`
+	AllocationTimeoutSeconds int      `env:"ALLOCATION_TIMEOUT_SECONDS, default=5"`
+	AllocationConcurrency    int      `env:"ALLOCATION_CONCURRENCY, default=4"`
+	RematchPolicy            string   `env:"REMATCH_POLICY, default=off"`
+	RematchWindowSeconds     int      `env:"REMATCH_WINDOW_SECONDS, default=600"`
+	RematchLastMatches       int      `env:"REMATCH_LAST_MATCHES, default=0"`
+	RematchPenaltyLevels     int      `env:"REMATCH_PENALTY_LEVELS, default=5"`
+	BlockListSize            int      `env:"BLOCK_LIST_SIZE, default=100"`
+	BlockFallbackSeconds     int      `env:"BLOCK_FALLBACK_SECONDS, default=0"`
+	Matcher                  string   `env:"MATCHER, default=greedy"`
+	MatchLevelWeight         float64  `env:"MATCH_LEVEL_WEIGHT, default=1"`
+	MatchWaitWeight          float64  `env:"MATCH_WAIT_WEIGHT, default=0.1"`
 }
 
 func (c MatchmakingConfig) DurationToFindGroup() time.Duration {
@@ -17,3 +39,68 @@ func (c MatchmakingConfig) DurationToFindGroup() time.Duration {
 func (c MatchmakingConfig) TimeoutDuration() time.Duration {
 	return time.Duration(c.MatchTimeoutAfterSeconds) * time.Second
 }
+
+// HealthStallDuration time without heartbeats after which a matchmaking goroutine is stalled
+func (c MatchmakingConfig) HealthStallDuration() time.Duration {
+	if c.HealthStallSeconds <= 0 {
+		return 10 * time.Second
+	}
+
+	return time.Duration(c.HealthStallSeconds) * time.Second
+}
+
+// RestartBackoff delay before the first restart of a panicked matchmaking goroutine, doubled on every next restart
+func (c MatchmakingConfig) RestartBackoff() time.Duration {
+	return time.Duration(c.RestartBackoffMillis) * time.Millisecond
+}
+
+// AllocationTimeout time to allocate a game server for a match
+func (c MatchmakingConfig) AllocationTimeout() time.Duration {
+	if c.AllocationTimeoutSeconds <= 0 {
+		return 5 * time.Second
+	}
+
+	return time.Duration(c.AllocationTimeoutSeconds) * time.Second
+}
+
+// RematchEnabled recent opponents are tracked with the penalty or block policy
+func (c MatchmakingConfig) RematchEnabled() bool {
+	return c.RematchPolicy == RematchPenalty || c.RematchPolicy == RematchBlock
+}
+
+// RematchWindow time players are recent opponents after their match
+func (c MatchmakingConfig) RematchWindow() time.Duration {
+	return time.Duration(c.RematchWindowSeconds) * time.Second
+}
+
+// BlockFallback wait time of both players after which blocked players are matched anyway, zero never matches them
+func (c MatchmakingConfig) BlockFallback() time.Duration {
+	return time.Duration(c.BlockFallbackSeconds) * time.Second
+}
+
+// Validate checks the settings which have no safe default
+func (c MatchmakingConfig) Validate() error {
+	switch c.RematchPolicy {
+	case RematchOff, RematchPenalty, RematchBlock:
+	default:
+		return fmt.Errorf("unknown REMATCH_POLICY %q, expected %q, %q or %q", c.RematchPolicy, RematchOff, RematchPenalty, RematchBlock)
+	}
+	switch c.Matcher {
+	case MatcherGreedy, MatcherOptimal:
+	default:
+		return fmt.Errorf("unknown MATCHER %q, expected %q or %q", c.Matcher, MatcherGreedy, MatcherOptimal)
+	}
+
+	return nil
+}
+
+// LevelBand returns the metrics label of the level range the level belongs to, e.g. "10-19"
+func (c MatchmakingConfig) LevelBand(level int) string {
+	size := max(c.LevelBandSize, 1)
+	start := level / size * size
+	if level < 0 && level%size != 0 {
+		start -= size
+	}
+
+	return fmt.Sprintf("%d-%d", start, start+size-1)
+}
@@ -0,0 +1,56 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLevelBand(t *testing.T) {
+	// Arrange
+	config := MatchmakingConfig{LevelBandSize: 10}
+	levels := map[int]string{
+		0:   "0-9",
+		9:   "0-9",
+		10:  "10-19",
+		125: "120-129",
+		-1:  "-10--1",
+	}
+
+	for level, expected := range levels {
+		// Act
+		band := config.LevelBand(level)
+
+		// Assert
+		assert.Equal(t, expected, band, level)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MatchmakingConfig
+		valid  bool
+	}{
+		{name: "rematch off", config: MatchmakingConfig{RematchPolicy: RematchOff, Matcher: MatcherGreedy}, valid: true},
+		{name: "rematch penalty", config: MatchmakingConfig{RematchPolicy: RematchPenalty, Matcher: MatcherGreedy}, valid: true},
+		{name: "rematch block", config: MatchmakingConfig{RematchPolicy: RematchBlock, Matcher: MatcherGreedy}, valid: true},
+		{name: "unknown rematch policy", config: MatchmakingConfig{RematchPolicy: "blok", Matcher: MatcherGreedy}},
+		{name: "empty rematch policy", config: MatchmakingConfig{Matcher: MatcherGreedy}},
+		{name: "optimal matcher", config: MatchmakingConfig{RematchPolicy: RematchOff, Matcher: MatcherOptimal}, valid: true},
+		{name: "unknown matcher", config: MatchmakingConfig{RematchPolicy: RematchOff, Matcher: "optimum"}},
+		{name: "empty matcher", config: MatchmakingConfig{RematchPolicy: RematchOff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.config.Validate()
+
+			// Assert
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package matchmaking
+
+// FanOut copies every session of the input to each of n outputs with the buffer size,
+// outputs are closed after the input. A consumer which stops reading blocks the others,
+// so every output must be read until it is closed.
+func FanOut(input <-chan MatchSession, n, size int) []<-chan MatchSession {
+	outputs := make([]chan MatchSession, n)
+	result := make([]<-chan MatchSession, n)
+	for i := range outputs {
+		outputs[i] = make(chan MatchSession, size)
+		result[i] = outputs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, output := range outputs {
+				close(output)
+			}
+		}()
+		for session := range input {
+			for _, output := range outputs {
+				output <- session
+			}
+		}
+	}()
+
+	return result
+}
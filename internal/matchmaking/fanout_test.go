@@ -0,0 +1,28 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFanOut(t *testing.T) {
+	// Arrange
+	input := make(chan MatchSession, 2)
+	first := NewMatchSession(ChangesTypeAdded, Player{ID: "1"})
+	second := NewMatchSession(ChangesTypeRemoved, Player{ID: "1"})
+	input <- first
+	input <- second
+	close(input)
+
+	// Act
+	outputs := FanOut(input, 2, 2)
+
+	// Assert
+	for _, output := range outputs {
+		var ids []string
+		for session := range output {
+			ids = append(ids, session.ID)
+		}
+		assert.Equal(t, []string{first.ID, second.ID}, ids)
+	}
+}
@@ -0,0 +1,142 @@
+package matchmaking
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type ComponentStatus = string
+
+const (
+	ComponentOk         ComponentStatus = "ok"
+	ComponentNotStarted ComponentStatus = "not_started"
+	ComponentStalled    ComponentStatus = "stalled"
+	ComponentFailed     ComponentStatus = "failed"
+	ComponentStopped    ComponentStatus = "stopped"
+)
+
+const (
+	componentCommands = "commands"
+	componentMatcher  = "matcher"
+)
+
+// ErrUnhealthy is returned when a matchmaking goroutine is stalled, failed or stopped
+var ErrUnhealthy = errors.New("matchmaking is unhealthy")
+
+// ComponentHealth health of a goroutine started by Start
+type ComponentHealth struct {
+	Status   ComponentStatus `json:"status"`
+	LastBeat time.Time       `json:"lastBeat,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Health of the service with details of every goroutine started by Start
+type Health struct {
+	State      ServiceState               `json:"state"`
+	Healthy    bool                       `json:"healthy"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// Err returns ErrUnhealthy with names of unhealthy components
+func (h Health) Err() error {
+	if h.Healthy {
+		return nil
+	}
+
+	var err error
+	for name, component := range h.Components {
+		if component.Status != ComponentOk {
+			err = errors.Join(err, fmt.Errorf("%s is %s", name, component.Status))
+		}
+	}
+
+	return fmt.Errorf("%w: %w", ErrUnhealthy, err)
+}
+
+// heartbeat tracks liveness of a goroutine
+type heartbeat struct {
+	// stallAfter time without beats after which the goroutine is stalled
+	stallAfter time.Duration
+	last       time.Time
+	err        error
+	stopped    bool
+	l          sync.RWMutex
+}
+
+func newHeartbeat(stallAfter time.Duration) *heartbeat {
+	return &heartbeat{stallAfter: stallAfter}
+}
+
+// beat marks the goroutine alive
+func (h *heartbeat) beat() {
+	h.l.Lock()
+	h.last = time.Now()
+	h.stopped = false
+	h.err = nil
+	h.l.Unlock()
+}
+
+// fail marks the goroutine dead because of the error
+func (h *heartbeat) fail(err error) {
+	h.l.Lock()
+	h.err = err
+	h.l.Unlock()
+}
+
+// stop marks the goroutine finished
+func (h *heartbeat) stop() {
+	h.l.Lock()
+	h.stopped = true
+	h.l.Unlock()
+}
+
+func (h *heartbeat) health() ComponentHealth {
+	h.l.RLock()
+	defer h.l.RUnlock()
+
+	component := ComponentHealth{LastBeat: h.last}
+	switch {
+	case h.err != nil:
+		component.Status = ComponentFailed
+		component.Error = h.err.Error()
+	case h.stopped:
+		component.Status = ComponentStopped
+	case h.last.IsZero():
+		component.Status = ComponentNotStarted
+	case time.Since(h.last) > h.stallAfter:
+		component.Status = ComponentStalled
+	default:
+		component.Status = ComponentOk
+	}
+
+	return component
+}
+
+// Health returns health of the goroutines started by Start, they are stalled when they
+// do not report a heartbeat within HEALTH_STALL_SECONDS or three matcher ticks.
+func (m *Service) Health() Health {
+	health := Health{
+		State:      m.State(),
+		Healthy:    true,
+		Components: make(map[string]ComponentHealth, len(m.heartbeats)),
+	}
+	for name, beat := range m.heartbeats {
+		component := beat.health()
+		health.Components[name] = component
+		health.Healthy = health.Healthy && component.Status == ComponentOk
+	}
+
+	return health
+}
+
+// newHeartbeats creates heartbeats of the goroutines started by Start
+func newHeartbeats(config MatchmakingConfig) map[string]*heartbeat {
+	stallAfter := config.HealthStallDuration()
+
+	return map[string]*heartbeat{
+		componentCommands: newHeartbeat(stallAfter),
+		componentMatcher:  newHeartbeat(max(stallAfter, 3*config.DurationToFindGroup())),
+	}
+}
@@ -0,0 +1,57 @@
+package matchmaking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestHeartbeatStatus(t *testing.T) {
+	synctest.Run(func() {
+		// Arrange
+		beat := newHeartbeat(time.Second)
+		notStarted := beat.health()
+
+		// Act
+		beat.beat()
+		ok := beat.health()
+		time.Sleep(time.Second * 2)
+		stalled := beat.health()
+		beat.stop()
+		stopped := beat.health()
+
+		// Assert
+		assert.Equal(t, ComponentNotStarted, notStarted.Status)
+		assert.Equal(t, ComponentOk, ok.Status)
+		assert.Equal(t, ComponentStalled, stalled.Status)
+		assert.Equal(t, ComponentStopped, stopped.Status)
+	})
+}
+
+func TestHealthOfRunningService(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:             10,
+		MinGroupSize:          2,
+		FindGroupEverySeconds: 1,
+	}, NewStorage(), metrics.Noop{})
+	before := service.Health()
+
+	// Act
+	ctx, cancelFunc := context.WithCancel(t.Context())
+	_, _ = service.Start(ctx)
+	running := service.Health()
+	cancelFunc()
+	assert.Eventually(t, func() bool {
+		return service.Health().Components[componentCommands].Status == ComponentStopped
+	}, time.Second, time.Millisecond*10)
+
+	// Assert
+	assert.ErrorIs(t, before.Err(), ErrUnhealthy)
+	assert.NoError(t, running.Err())
+	assert.Len(t, running.Components, 2)
+	assert.ErrorIs(t, service.Ready(), ErrUnhealthy)
+}
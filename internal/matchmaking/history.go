@@ -0,0 +1,53 @@
+package matchmaking
+
+import "sync"
+
+// sessionHistory keeps the latest match sessions in a ring buffer
+type sessionHistory struct {
+	sessions []MatchSession
+	next     int
+	full     bool
+	l        sync.RWMutex
+}
+
+func newSessionHistory(size int) *sessionHistory {
+	return &sessionHistory{
+		sessions: make([]MatchSession, max(size, 0)),
+	}
+}
+
+// add stores the session, overwriting the oldest one when the history is full
+func (h *sessionHistory) add(session MatchSession) {
+	if len(h.sessions) == 0 {
+		return
+	}
+
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	h.sessions[h.next] = session
+	h.next = (h.next + 1) % len(h.sessions)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// latest returns up to limit sessions starting from the newest one
+func (h *sessionHistory) latest(limit int) []MatchSession {
+	h.l.RLock()
+	defer h.l.RUnlock()
+
+	count := h.next
+	if h.full {
+		count = len(h.sessions)
+	}
+	if limit > 0 {
+		count = min(count, limit)
+	}
+
+	sessions := make([]MatchSession, 0, count)
+	for i := 1; i <= count; i++ {
+		sessions = append(sessions, h.sessions[(h.next-i+len(h.sessions))%len(h.sessions)])
+	}
+	return sessions
+}
@@ -0,0 +1,28 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSessionHistoryKeepsLatest(t *testing.T) {
+	// Arrange
+	history := newSessionHistory(3)
+
+	// Act
+	for _, id := range []string{"1", "2", "3", "4"} {
+		history.add(MatchSession{ID: id})
+	}
+
+	// Assert
+	ids := func(sessions []MatchSession) []string {
+		result := make([]string, 0, len(sessions))
+		for _, s := range sessions {
+			result = append(result, s.ID)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"4", "3", "2"}, ids(history.latest(0)))
+	assert.Equal(t, []string{"4", "3"}, ids(history.latest(2)))
+	assert.Empty(t, newSessionHistory(0).latest(0))
+}
@@ -0,0 +1,80 @@
+package matchmaking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+)
+
+func newLifecycleService() *Service {
+	return NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:             10,
+		MinGroupSize:          2,
+		FindGroupEverySeconds: 1,
+	}, NewStorage(), metrics.Noop{})
+}
+
+func TestStartTwice(t *testing.T) {
+	// Arrange
+	service := newLifecycleService()
+
+	// Act
+	_, errFirst := service.Start(t.Context())
+	_, errSecond := service.Start(t.Context())
+	_, errRun := service.Run(t.Context())
+
+	// Assert
+	assert.NoError(t, errFirst)
+	assert.ErrorIs(t, errSecond, ErrAlreadyStarted)
+	assert.ErrorIs(t, errRun, ErrAlreadyStarted)
+}
+
+func TestStopAndWait(t *testing.T) {
+	// Arrange
+	service := newLifecycleService()
+	output, err := service.Start(t.Context())
+	assert.NoError(t, err)
+
+	// Act
+	errStop := service.Stop(t.Context())
+	errWait := service.Wait()
+
+	// Assert
+	assert.NoError(t, errStop)
+	assert.NoError(t, errWait)
+	_, ok := <-output
+	assert.False(t, ok, "output should be closed")
+	_, errStart := service.Start(t.Context())
+	assert.ErrorIs(t, errStart, ErrStopped)
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	// Arrange
+	service := newLifecycleService()
+
+	// Act
+	errStop := service.Stop(t.Context())
+	_, errStart := service.Start(t.Context())
+
+	// Assert
+	assert.NoError(t, errStop)
+	assert.ErrorIs(t, errStart, ErrStopped)
+	assert.NoError(t, service.Wait())
+}
+
+func TestWaitReportsCanceledContext(t *testing.T) {
+	// Arrange
+	service := newLifecycleService()
+	ctx, cancelFunc := context.WithCancel(t.Context())
+	_, err := service.Start(ctx)
+	assert.NoError(t, err)
+
+	// Act
+	cancelFunc()
+	errWait := service.Wait()
+
+	// Assert
+	assert.ErrorIs(t, errWait, context.Canceled)
+	assert.ErrorIs(t, service.Err(), context.Canceled)
+}
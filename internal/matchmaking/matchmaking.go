@@ -1,9 +1,17 @@
 package matchmaking
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
-	"math"
+	"matchmaking/internal/metrics"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,19 +22,29 @@ const (
 	removePlayerCommand
 	timeoutPlayerCommand
 	createMatchCommand
+	stateCommand
+	shutdownCommand
 )
 
 type queueCommand struct {
 	players     []Player
 	requestTime time.Time
 	command     playerCommand
+	// state entered by the service for stateCommand
+	state ServiceState
+	// matchID and endpoint of the game server allocated for createMatchCommand
+	matchID  string
+	endpoint *Endpoint
+	// spanContext of the operation which issued the command
+	spanContext trace.SpanContext
 }
 
-func newQueueCommand(command playerCommand, players ...Player) queueCommand {
+func newQueueCommand(ctx context.Context, command playerCommand, players ...Player) queueCommand {
 	return queueCommand{
 		players:     players,
 		requestTime: time.Now(),
 		command:     command,
+		spanContext: trace.SpanContextFromContext(ctx),
 	}
 
 }
@@ -42,31 +60,137 @@ func (qc queueCommand) storedPlayers() []StoredPlayer {
 	return storedPlayers
 }
 
+var (
+	// ErrPlayerNotFound is returned when a player is not waiting in the queue
+	ErrPlayerNotFound = errors.New("player not found")
+	// ErrStopped is returned when the service has been shut down
+	ErrStopped = errors.New("matchmaking service is stopped")
+	// ErrAlreadyStarted is returned when the service is started twice
+	ErrAlreadyStarted = errors.New("matchmaking service is already started")
+)
+
 type Service struct {
 	queue   chan queueCommand
 	config  MatchmakingConfig
 	storage *Storage
+	history *sessionHistory
+	recent  *recentOpponents
+	blocks  *BlockList
+	state   atomic.Value
 	logger  *slog.Logger
+	metrics metrics.Recorder
+	// allocator is optional, see SetAllocator
+	allocator Allocator
+	// allocationSlots bounds allocations running at once, allocations waits for them
+	allocationSlots chan struct{}
+	allocations     sync.WaitGroup
+	// reserved players wait for the game server of their match
+	reserved  map[string]bool
+	reservedL sync.Mutex
+	// stopped is closed when the command loop exits
+	stopped    chan struct{}
+	heartbeats map[string]*heartbeat
+	// done is closed when both goroutines exit
+	done chan struct{}
+	// err stopped the service, see Err
+	err       atomic.Pointer[error]
+	lifecycle sync.Mutex
+	started   bool
 }
 
-// NewService creates a new matchmaking service with the provided configuration and storage.
-func NewService(logger *slog.Logger, config MatchmakingConfig, storage *Storage) *Service {
-	return &Service{
-		config:  config,
-		logger:  logger,
-		storage: storage,
-		queue:   make(chan queueCommand, config.QueueSize),
+// NewService creates a new matchmaking service with the provided configuration, storage and metrics,
+// use metrics.Noop{} to skip metrics.
+func NewService(logger *slog.Logger, config MatchmakingConfig, storage *Storage, recorder metrics.Recorder) *Service {
+	service := &Service{
+		config:          config,
+		logger:          logger,
+		storage:         storage,
+		history:         newSessionHistory(config.SessionHistorySize),
+		recent:          newRecentOpponents(config),
+		blocks:          NewBlockList(config.BlockListSize),
+		metrics:         recorder,
+		queue:           make(chan queueCommand, config.QueueSize),
+		reserved:        make(map[string]bool),
+		allocationSlots: make(chan struct{}, max(config.AllocationConcurrency, 1)),
+		stopped:         make(chan struct{}),
+		done:            make(chan struct{}),
 	}
+	service.heartbeats = newHeartbeats(config)
+	service.state.Store(StateRunning)
+
+	return service
+}
+
+// AddPlayer adds a player to the matchmaking queue, returns ErrNotAccepting when the queue is paused or draining
+// and ErrAvoidListTooLong when a player avoids too many players.
+func (m *Service) AddPlayer(player ...Player) error {
+	return m.AddPlayerContext(context.Background(), player...)
 }
 
-// AddPlayer adds a player to the matchmaking queue.
-func (m *Service) AddPlayer(player ...Player) {
-	m.queue <- newQueueCommand(addPlayerCommand, player...)
+// AddPlayerContext adds a player to the matchmaking queue, continuing the trace from ctx.
+func (m *Service) AddPlayerContext(ctx context.Context, player ...Player) error {
+	if err := m.accepting(); err != nil {
+		return err
+	}
+	if err := m.validateAvoid(player); err != nil {
+		return err
+	}
+
+	ctx, span := startEnqueueSpan(ctx, addPlayerCommand, player)
+	defer span.End()
+
+	return m.enqueue(ctx, newQueueCommand(ctx, addPlayerCommand, player...))
 }
 
 // RemovePlayer removes a player from the matchmaking queue.
-func (m *Service) RemovePlayer(player ...Player) {
-	m.queue <- newQueueCommand(removePlayerCommand, player...)
+func (m *Service) RemovePlayer(player ...Player) error {
+	return m.RemovePlayerContext(context.Background(), player...)
+}
+
+// RemovePlayerContext removes a player from the matchmaking queue, continuing the trace from ctx.
+func (m *Service) RemovePlayerContext(ctx context.Context, player ...Player) error {
+	ctx, span := startEnqueueSpan(ctx, removePlayerCommand, player)
+	defer span.End()
+
+	return m.enqueue(ctx, newQueueCommand(ctx, removePlayerCommand, player...))
+}
+
+// CreateMatch creates a match session from the waiting players regardless of their levels.
+func (m *Service) CreateMatch(ctx context.Context, playerIDs ...string) error {
+	if len(playerIDs) == 0 {
+		return fmt.Errorf("no players provided")
+	}
+
+	players := make([]Player, 0, len(playerIDs))
+	for _, id := range playerIDs {
+		stored, ok := m.storage.GetPlayer(id)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrPlayerNotFound, id)
+		}
+		players = append(players, stored.Player)
+	}
+
+	qc, err := m.newMatchCommand(ctx, players...)
+	if err != nil {
+		return err
+	}
+
+	return m.enqueue(ctx, qc)
+}
+
+// WaitingPlayers returns players waiting in the queue sorted by level.
+func (m *Service) WaitingPlayers() []StoredPlayer {
+	return m.storage.GetSortedByLevelPlayers()
+}
+
+// GetPlayer returns the player waiting in the queue.
+func (m *Service) GetPlayer(id string) (StoredPlayer, bool) {
+	return m.storage.GetPlayer(id)
+}
+
+// RecentSessions returns up to limit latest match sessions starting from the newest one, all kept sessions when limit is 0.
+func (m *Service) RecentSessions(limit int) []MatchSession {
+	return m.history.latest(limit)
 }
 
 // PlayersInQueue returns the total number of players in the matchmaking queue.
@@ -74,131 +198,429 @@ func (m *Service) PlayersInQueue() int {
 	return m.storage.TotalPlayers()
 }
 
-// Run starts the matchmaking service and returns a channel with match sessions.
-func (m *Service) Run(ctx context.Context) <-chan MatchSession {
+// QueueLength returns the number of commands waiting to be processed.
+func (m *Service) QueueLength() int {
+	return len(m.queue)
+}
+
+// QueueCapacity returns the maximum number of commands waiting to be processed.
+func (m *Service) QueueCapacity() int {
+	return cap(m.queue)
+}
+
+// QueueName returns the name of the matchmaking queue.
+func (m *Service) QueueName() string {
+	return m.config.QueueName
+}
+
+// Stop stops accepting players and forming matches, notifies waiting players and saves them
+// to the snapshot file when it is configured. The channel returned by Start is closed after that.
+func (m *Service) Stop(ctx context.Context) error {
+	m.lifecycle.Lock()
+	m.state.Store(StateStopping)
+	if !m.started {
+		// nothing to wait for, Start fails from now on
+		m.started = true
+		close(m.stopped)
+		close(m.done)
+	}
+	m.lifecycle.Unlock()
+
+	err := m.enqueue(ctx, newQueueCommand(ctx, shutdownCommand))
+	if err != nil && !errors.Is(err, ErrStopped) {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.stopped:
+		return nil
+	}
+}
+
+// Wait blocks until both goroutines of the service exit and returns the reason, see Err.
+func (m *Service) Wait() error {
+	<-m.done
+	return m.Err()
+}
+
+// enqueue sends the command to the command loop unless the service is stopped
+func (m *Service) enqueue(ctx context.Context, qc queueCommand) error {
+	// prefer the stopped service over free space in the queue
+	select {
+	case <-m.stopped:
+		return ErrStopped
+	default:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.stopped:
+		return ErrStopped
+	case m.queue <- qc:
+		return nil
+	}
+}
+
+// Start starts the matchmaking service and returns a channel with match sessions,
+// the service can be started only once.
+func (m *Service) Start(ctx context.Context) (<-chan MatchSession, error) {
+	m.lifecycle.Lock()
+	defer m.lifecycle.Unlock()
+
+	if m.started {
+		if m.State() == StateStopping {
+			return nil, ErrStopped
+		}
+		return nil, ErrAlreadyStarted
+	}
+	m.started = true
+
+	return m.run(ctx), nil
+}
+
+// Run starts the matchmaking service and returns a channel with match sessions,
+// returns the error of Start when the service has been started.
+func (m *Service) Run(ctx context.Context) (<-chan MatchSession, error) {
+	return m.Start(ctx)
+}
+
+func (m *Service) run(ctx context.Context) <-chan MatchSession {
 	matchOutput := make(chan MatchSession, m.config.QueueSize)
+	m.restoreSnapshot(ctx)
+
+	// both loops stop when restarts of either one are exhausted
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	// done is closed by the last exited goroutine
+	running := atomic.Int32{}
+	running.Store(2)
+	exit := func() {
+		if running.Add(-1) == 0 {
+			cancel(nil)
+			close(m.done)
+		}
+	}
 
-	// start receiving commands
+	// heartbeats start before goroutines, so the service is healthy right after Start
+	m.heartbeats[componentCommands].beat()
+	m.heartbeats[componentMatcher].beat()
 	go func() {
+		defer exit()
+		defer close(m.stopped)
 		defer close(matchOutput)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case qc := <-m.queue:
-				if len(qc.players) == 0 {
-					continue
-				}
-				switch qc.command {
-				case timeoutPlayerCommand:
-					removedPlayers := m.storage.RemovePlayers(qc.storedPlayers())
-					matchOutput <- NewMatchSession(ChangesTypeTimeout, removedPlayers...)
-				case createMatchCommand:
-					removedPlayers := m.storage.RemovePlayers(qc.storedPlayers())
-					matchOutput <- NewMatchSession(ChangesTypeMatchFound, removedPlayers...)
-				case removePlayerCommand:
-					removedPlayers := m.storage.RemovePlayers(qc.storedPlayers())
-					matchOutput <- NewMatchSession(ChangesTypeRemoved, removedPlayers...)
-				case addPlayerCommand:
-					// TODO: check if player already exists
-					m.storage.AddPlayers(qc.storedPlayers())
-					matchOutput <- NewMatchSession(ChangesTypeAdded, qc.players...)
+		err := m.supervise(ctx, componentCommands, func(ctx context.Context) {
+			m.runCommands(ctx, matchOutput)
+		})
+		m.terminate(err, cancel)
+		// the output is closed after the reason is known
+		if cause := context.Cause(ctx); cause != nil {
+			m.err.CompareAndSwap(nil, &cause)
+		}
+		// matching is pointless without the command loop
+		cancel(ErrStopped)
+	}()
+	go func() {
+		defer exit()
+		defer m.allocations.Wait()
+		m.terminate(m.supervise(ctx, componentMatcher, m.runMatcher), cancel)
+	}()
+
+	return matchOutput
+}
+
+// runCommands receives commands until ctx is done or the service is shut down
+func (m *Service) runCommands(ctx context.Context, matchOutput chan<- MatchSession) {
+	beat := m.heartbeats[componentCommands]
+	defer beat.stop()
+
+	send := func(session MatchSession) {
+		select {
+		case <-ctx.Done():
+		case matchOutput <- session:
+		}
+	}
+	for {
+		beat.beat()
+		select {
+		case <-ctx.Done():
+			return
+		case qc := <-m.queue:
+			m.observeCommandQueue()
+			if qc.command == shutdownCommand {
+				if session, ok := m.shutdown(qc); ok {
+					send(session)
 				}
-			default:
-				time.Sleep(time.Millisecond * 10)
+				return
+			}
+			if len(qc.players) == 0 && qc.command != stateCommand {
+				continue
+			}
+			session := m.handleCommand(qc)
+			// nobody to notify about the state change or the match which is not created
+			if (qc.command == stateCommand || qc.command == createMatchCommand) && len(session.Players) == 0 {
+				continue
 			}
+			send(session)
+		default:
+			time.Sleep(time.Millisecond * 10)
 		}
-	}()
+	}
+}
 
-	// start matchmaking
-	go func() {
-		// try to find a match session every tick
-		for {
-			select {
-			case <-ctx.Done():
+// runMatcher tries to find a match session every tick until ctx is done or the service is shut down
+func (m *Service) runMatcher(ctx context.Context) {
+	beat := m.heartbeats[componentMatcher]
+	defer beat.stop()
+
+	for {
+		beat.beat()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.config.DurationToFindGroup()):
+			if m.State() == StateStopping {
 				return
-			case <-time.After(m.config.DurationToFindGroup()):
-				if m.storage.TotalPlayers() == 0 {
-					continue
-				}
+			}
+			if m.storage.TotalPlayers() == 0 {
+				m.observeQueue(nil)
+				continue
+			}
 
-				// split by expired and actual players
-				allWaitingPlayers := m.storage.GetSortedByLevelPlayers()
-				var expiredPlayers []Player
-				var players []Player
-				for _, p := range allWaitingPlayers {
-					if time.Since(p.Created) > m.config.TimeoutDuration() {
-						expiredPlayers = append(expiredPlayers, p.Player)
-					} else {
-						players = append(players, p.Player)
-					}
-				}
-				if len(expiredPlayers) > 0 {
-					m.queue <- newQueueCommand(timeoutPlayerCommand, expiredPlayers...)
-				}
+			m.matchTick(ctx)
+		}
+	}
+}
 
-				// try to find a match for each player
-				count := 0
-				buffer := make([]Player, 0, m.config.MinGroupSize)
-				for i := 0; i < len(players); i++ {
-					buffer = buffer[:0]
-					player := players[i]
-					matchPlayers, lastIndex := m.findMatch(players[i:], player, buffer)
-					if len(matchPlayers) < m.config.MinGroupSize {
-						continue
-					}
-					copyPlayers := make([]Player, len(matchPlayers))
-					copy(copyPlayers, matchPlayers)
-					m.queue <- newQueueCommand(createMatchCommand, copyPlayers...)
-					i += lastIndex
-					count++
-				}
+// handleCommand applies the command to the storage and returns the session describing the changes
+func (m *Service) handleCommand(qc queueCommand) MatchSession {
+	var session MatchSession
+	var removedPlayers []StoredPlayer
+	switch qc.command {
+	case timeoutPlayerCommand:
+		removedPlayers = m.storage.RemovePlayers(qc.storedPlayers())
+		endWaitSpans(qc, ChangesTypeTimeout, removedPlayers)
+		session = NewMatchSession(ChangesTypeTimeout, toPlayers(removedPlayers)...)
+	case createMatchCommand:
+		m.unreserve(qc.players)
+		removedPlayers = m.storage.RemovePlayers(qc.storedPlayers())
+		if len(removedPlayers) < len(qc.players) {
+			// some players left while the game server was allocated, the rest waits for another match
+			m.storage.AddPlayers(removedPlayers)
+			m.release(qc)
+			m.logger.Debug("Match is not created, players left the queue", slog.String("match_id", qc.matchID))
+			return MatchSession{}
+		}
+		endWaitSpans(qc, ChangesTypeMatchFound, removedPlayers)
+		session = NewMatchSession(ChangesTypeMatchFound, toPlayers(removedPlayers)...)
+		session.ID = qc.matchID
+		session.Endpoint = qc.endpoint
+		session.Quality = m.matchQuality(session.Players)
+		if m.config.RematchEnabled() {
+			m.recent.record(session.Players, session.Created)
+		}
+	case removePlayerCommand:
+		removedPlayers = m.storage.RemovePlayers(qc.storedPlayers())
+		endWaitSpans(qc, ChangesTypeRemoved, removedPlayers)
+		session = NewMatchSession(ChangesTypeRemoved, toPlayers(removedPlayers)...)
+	case addPlayerCommand:
+		// the state may have changed after the command was queued
+		if !acceptsPlayers(m.State()) {
+			session = NewMatchSession(ChangesTypeRejected, qc.players...)
+			break
+		}
+		// TODO: check if player already exists
+		m.storage.AddPlayers(startWaitSpans(qc, qc.storedPlayers()))
+		session = NewMatchSession(ChangesTypeAdded, qc.players...)
+	case stateCommand:
+		session = NewMatchSession(stateChangesType(qc.state), toPlayers(m.storage.GetSortedByLevelPlayers())...)
+	}
+	m.observeSession(session, removedPlayers)
+	if len(session.Players) > 0 {
+		m.history.add(session)
+	}
 
-				if count > 0 {
-					m.logger.InfoContext(ctx, "Matchmaking by tick:",
-						slog.Int("players_matched", count*m.config.MinGroupSize),
-						slog.Int("total_players", len(players)))
-				}
+	return session.withSpanContext(qc.spanContext)
+}
 
-				// TODO: create not full group after some time
-				// TODO: increase level diff after some time
+// matchTick times out expired players and tries to find match sessions for the rest
+func (m *Service) matchTick(ctx context.Context) {
+	defer m.observeTick(time.Now())
+	ctx, tickSpan := tracer.Start(ctx, "matchmaking.tick")
+	defer tickSpan.End()
+
+	// split by expired and actual players
+	allWaitingPlayers := m.storage.GetSortedByLevelPlayers()
+	m.observeQueue(allWaitingPlayers)
+	var expiredPlayers []Player
+	var players []StoredPlayer
+	for _, p := range allWaitingPlayers {
+		if time.Since(p.Created) > m.config.TimeoutDuration() {
+			expiredPlayers = append(expiredPlayers, p.Player)
+		} else {
+			players = append(players, p)
+		}
+	}
+	if len(expiredPlayers) > 0 {
+		_ = m.enqueue(ctx, newQueueCommand(ctx, timeoutPlayerCommand, expiredPlayers...))
+	}
+
+	// waiting players only time out while matches are not formed
+	if !formsMatches(m.State()) {
+		return
+	}
+
+	// try to find a match for each player
+	now := time.Now()
+	m.recent.prune(now)
+	players = m.unreserved(players)
+	count := 0
+	for _, group := range m.findGroups(players, now) {
+		matchPlayers := toPlayers(group)
+		if m.allocator != nil {
+			// allocations run outside the tick, the rest of the groups waits for free slots
+			if !m.allocate(ctx, matchPlayers) {
+				break
 			}
+			count++
+			continue
 		}
-	}()
+		matchCtx, matchSpan := startMatchSpan(ctx, matchPlayers)
+		qc, err := m.newMatchCommand(matchCtx, matchPlayers...)
+		if err != nil {
+			// the players stay in the queue, game servers are likely unavailable for the rest of them too
+			matchSpan.End()
+			break
+		}
+		err = m.enqueue(matchCtx, qc)
+		matchSpan.End()
+		if err != nil {
+			return
+		}
+		count++
+	}
 
-	return matchOutput
+	tickSpan.SetAttributes(
+		attribute.Int("matchmaking.matches", count),
+		attribute.Int("matchmaking.waiting_players", len(players)),
+		attribute.Int("matchmaking.expired_players", len(expiredPlayers)))
+	if count > 0 {
+		m.logger.InfoContext(ctx, "Matchmaking by tick:",
+			slog.Int("players_matched", count*m.config.MinGroupSize),
+			slog.Int("total_players", len(players)))
+	}
+
+	// TODO: create not full group after some time
+	// TODO: increase level diff after some time
 }
 
-// Match players within a simple Elo range
-func (m *Service) findMatch(players []Player, target Player, bestMatch []Player) ([]Player, int) {
-	if len(players) < m.config.MinGroupSize {
-		return nil, 0
+// findGroups splits players sorted by level into groups by the configured matcher
+func (m *Service) findGroups(players []StoredPlayer, now time.Time) [][]StoredPlayer {
+	if m.config.Matcher == MatcherOptimal {
+		return m.optimalGroups(players, now)
 	}
 
-	bestMatch = append(bestMatch, target)
+	return m.greedyGroups(players, now)
+}
 
-	lastIndex := 0
+// greedyGroups anchors groups at the longest waiting players, every anchor takes the best scored players left.
+// A group leaving the other players fewer groups than they form by level is skipped for the next oldest anchor,
+// so long waiters do not strand players at the ends of the level range.
+func (m *Service) greedyGroups(players []StoredPlayer, now time.Time) [][]StoredPlayer {
+	anchors := slices.Clone(players)
+	slices.SortStableFunc(anchors, func(a, b StoredPlayer) int {
+		return a.Created.Compare(b.Created)
+	})
 
-	for i, p := range players {
-		if p.ID == target.ID { // Check to skip self
+	var groups [][]StoredPlayer
+	left := slices.Clone(players)
+	grouped := make(map[string]bool)
+	buffer := make([]StoredPlayer, 0, m.config.MinGroupSize)
+	for _, anchor := range anchors {
+		if grouped[anchor.ID] {
+			continue
+		}
+		matchPlayers := m.findMatch(left, anchor, buffer[:0], now)
+		if len(matchPlayers) < m.config.MinGroupSize {
 			continue
 		}
-		diff := int(math.Abs(float64(p.Level - target.Level)))
-		if diff <= m.config.MaxLevelDiff {
-			bestMatch = append(bestMatch, p)
+		rest := slices.DeleteFunc(slices.Clone(left), func(p StoredPlayer) bool {
+			return slices.ContainsFunc(matchPlayers, func(g StoredPlayer) bool {
+				return g.ID == p.ID
+			})
+		})
+		if m.levelGroups(rest)+1 < m.levelGroups(left) {
+			continue
 		}
+		for _, p := range matchPlayers {
+			grouped[p.ID] = true
+		}
+		groups = append(groups, slices.Clone(matchPlayers))
+		left = rest
+	}
+
+	return groups
+}
 
+// levelGroups counts the groups the players sorted by level form of neighbours within the level range,
+// the most groups possible when only levels restrict them
+func (m *Service) levelGroups(players []StoredPlayer) int {
+	size := max(m.config.MinGroupSize, 1)
+	groups := 0
+	for i := 0; i+size <= len(players); {
+		if players[i+size-1].Level-players[i].Level <= m.config.MaxLevelDiff {
+			groups++
+			i += size
+			continue
+		}
+		i++
+	}
+
+	return groups
+}
+
+// findMatch groups the target with the best scored players of the players sorted by level, see candidateScore,
+// the level spread of the group stays within the range. Blocked pairs are skipped and recent opponents are skipped
+// or penalized by the rematch policy.
+func (m *Service) findMatch(players []StoredPlayer, target StoredPlayer, bestMatch []StoredPlayer, now time.Time) []StoredPlayer {
+	if len(players) < m.config.MinGroupSize {
+		return nil
+	}
+
+	byLevel := func(p StoredPlayer, level int) int {
+		return cmp.Compare(p.Level, level)
+	}
+	from, _ := slices.BinarySearchFunc(players, target.Level-m.config.MaxLevelDiff, byLevel)
+	to, _ := slices.BinarySearchFunc(players, target.Level+m.config.MaxLevelDiff+1, byLevel)
+	candidates := make([]StoredPlayer, 0, to-from)
+	for _, p := range players[from:to] {
+		if p.ID != target.ID {
+			candidates = append(candidates, p)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b StoredPlayer) int {
+		return cmp.Compare(m.candidateScore(target, a, now), m.candidateScore(target, b, now))
+	})
+
+	bestMatch = append(bestMatch, target)
+	lowest, highest := target.Level, target.Level
+	for _, p := range candidates {
 		if len(bestMatch) == m.config.MinGroupSize {
-			lastIndex = i
 			break
 		}
+		cost, ok := m.groupCost(bestMatch, p, now)
+		if !ok || max(highest, p.Level)-min(lowest, p.Level)+cost > m.config.MaxLevelDiff {
+			continue
+		}
+		bestMatch = append(bestMatch, p)
+		lowest, highest = min(lowest, p.Level), max(highest, p.Level)
 	}
 
 	if len(bestMatch) == m.config.MinGroupSize {
-		return bestMatch, lastIndex
+		return bestMatch
 	}
 
-	bestMatch = bestMatch[:0]
-
-	return nil, 0
+	return nil
 }
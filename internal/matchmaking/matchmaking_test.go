@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io"
 	"log/slog"
+	"matchmaking/internal/metrics"
 	"slices"
 	"testing"
 	"testing/synctest"
@@ -24,7 +25,7 @@ func TestMatchSessionFound(t *testing.T) {
 		FindGroupEverySeconds:    1,
 		MaxLevelDiff:             1,
 		MatchTimeoutAfterSeconds: 60,
-	}, storage)
+	}, storage, metrics.Noop{})
 	players := []Player{
 		{ID: "1", Level: 1},
 		{ID: "2", Level: 10},
@@ -37,7 +38,7 @@ func TestMatchSessionFound(t *testing.T) {
 	// Act
 	synctest.Run(func() {
 		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
-		output := service.Run(ctx)
+		output, _ := service.Run(ctx)
 
 		for _, p := range players {
 			service.AddPlayer(p)
@@ -72,7 +73,7 @@ func TestMatchSessionAllFound(t *testing.T) {
 		FindGroupEverySeconds:    1,
 		MaxLevelDiff:             10,
 		MatchTimeoutAfterSeconds: 60,
-	}, storage)
+	}, storage, metrics.Noop{})
 	players := []Player{
 		{ID: "1", Level: 1},
 		{ID: "2", Level: 1},
@@ -85,7 +86,7 @@ func TestMatchSessionAllFound(t *testing.T) {
 	// Act
 	synctest.Run(func() {
 		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
-		output := service.Run(ctx)
+		output, _ := service.Run(ctx)
 
 		for _, p := range players {
 			service.AddPlayer(p)
@@ -123,7 +124,7 @@ func TestMatchSessionNotFound(t *testing.T) {
 		FindGroupEverySeconds:    1,
 		MaxLevelDiff:             1,
 		MatchTimeoutAfterSeconds: 60,
-	}, storage)
+	}, storage, metrics.Noop{})
 	players := []Player{
 		{ID: "1", Level: 1},
 		{ID: "2", Level: 10},
@@ -136,7 +137,7 @@ func TestMatchSessionNotFound(t *testing.T) {
 	// Act
 	synctest.Run(func() {
 		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
-		output := service.Run(ctx)
+		output, _ := service.Run(ctx)
 
 		for _, p := range players {
 			service.AddPlayer(p)
@@ -174,7 +175,7 @@ func TestMatchSessionRemovePlayerFromQueue(t *testing.T) {
 		FindGroupEverySeconds:    1,
 		MaxLevelDiff:             1,
 		MatchTimeoutAfterSeconds: 60,
-	}, storage)
+	}, storage, metrics.Noop{})
 	ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
 
 	// Act
@@ -187,7 +188,7 @@ func TestMatchSessionRemovePlayerFromQueue(t *testing.T) {
 		{ID: "6", Level: 50},
 	}
 
-	output := service.Run(ctx)
+	output, _ := service.Run(ctx)
 
 	for _, p := range players {
 		service.AddPlayer(p)
@@ -231,7 +232,7 @@ func TestMatchSessionPlayerTimeout(t *testing.T) {
 		FindGroupEverySeconds:    1,
 		MaxLevelDiff:             1,
 		MatchTimeoutAfterSeconds: 1,
-	}, storage)
+	}, storage, metrics.Noop{})
 	players := []Player{
 		{ID: "1", Level: 1},
 	}
@@ -239,7 +240,7 @@ func TestMatchSessionPlayerTimeout(t *testing.T) {
 	// Act
 	synctest.Run(func() {
 		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
-		output := service.Run(ctx)
+		output, _ := service.Run(ctx)
 		for _, p := range players {
 			service.AddPlayer(p)
 		}
@@ -271,7 +272,7 @@ func TestMatchSessionEmptyQueue(t *testing.T) {
 		FindGroupEverySeconds:    1,
 		MaxLevelDiff:             1,
 		MatchTimeoutAfterSeconds: 1,
-	}, storage)
+	}, storage, metrics.Noop{})
 	players := []Player{
 		{ID: "1", Level: 1},
 		{ID: "2", Level: 10},
@@ -283,7 +284,7 @@ func TestMatchSessionEmptyQueue(t *testing.T) {
 	// Act
 	synctest.Run(func() {
 		ctx, _ := context.WithTimeout(t.Context(), time.Second*5)
-		output := service.Run(ctx)
+		output, _ := service.Run(ctx)
 		for _, p := range players {
 			service.AddPlayer(p)
 		}
@@ -2,12 +2,16 @@ package matchmaking
 
 import (
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
 	"time"
 )
 
 type Player struct {
 	ID    string `json:"id"`
 	Level int    `json:"level"`
+	// Avoid players who must not be in the match of this player
+	Avoid []string `json:"avoid,omitempty"`
 }
 
 type PlayerChangesType = string
@@ -17,6 +21,11 @@ const (
 	ChangesTypeRemoved    PlayerChangesType = "removed"
 	ChangesTypeTimeout    PlayerChangesType = "timeout"
 	ChangesTypeMatchFound PlayerChangesType = "matched"
+	ChangesTypeRejected   PlayerChangesType = "rejected"
+	ChangesTypePaused     PlayerChangesType = "paused"
+	ChangesTypeResumed    PlayerChangesType = "resumed"
+	ChangesTypeDraining   PlayerChangesType = "draining"
+	ChangesTypeShutdown   PlayerChangesType = "shutdown"
 )
 
 type MatchSession struct {
@@ -24,13 +33,33 @@ type MatchSession struct {
 	Created time.Time         `json:"created"`
 	Players []Player          `json:"players"`
 	Type    PlayerChangesType `json:"type"`
+	// Endpoint of the game server allocated for a found match
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+	// Quality of a found match from 0 to 1
+	Quality float64 `json:"quality,omitempty"`
+	// spanContext of the operation which produced the session
+	spanContext trace.SpanContext
 }
 
+// NewMatchSession creates a session of the players, their avoid lists are private and left out
 func NewMatchSession(t PlayerChangesType, players ...Player) MatchSession {
 	return MatchSession{
 		ID:      uuid.NewString(),
 		Created: time.Now(),
-		Players: players,
+		Players: withoutAvoid(players),
 		Type:    t,
 	}
 }
+
+func withoutAvoid(players []Player) []Player {
+	if !slices.ContainsFunc(players, func(p Player) bool { return len(p.Avoid) > 0 }) {
+		return players
+	}
+
+	public := make([]Player, 0, len(players))
+	for _, p := range players {
+		p.Avoid = nil
+		public = append(public, p)
+	}
+	return public
+}
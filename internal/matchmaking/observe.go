@@ -0,0 +1,59 @@
+package matchmaking
+
+import (
+	"time"
+)
+
+// observeCommandQueue samples usage of the command queue
+func (m *Service) observeCommandQueue() {
+	m.metrics.CommandQueue(len(m.queue), cap(m.queue))
+}
+
+// observeSession records player changes, wait time of removed players and level spread of matches
+func (m *Service) observeSession(session MatchSession, removedPlayers []StoredPlayer) {
+	m.metrics.PlayersChanged(session.Type, len(session.Players))
+
+	switch session.Type {
+	case ChangesTypeMatchFound:
+		for _, p := range removedPlayers {
+			m.metrics.TimeToMatch(session.Created.Sub(p.Created))
+		}
+		if len(session.Players) > 0 {
+			m.metrics.MatchLevelSpread(levelSpread(session.Players))
+		}
+	case ChangesTypeTimeout:
+		for _, p := range removedPlayers {
+			m.metrics.TimeToTimeout(session.Created.Sub(p.Created))
+		}
+	}
+}
+
+// observeQueue records waiting players by level band
+func (m *Service) observeQueue(players []StoredPlayer) {
+	bands := make(map[string]int)
+	for _, p := range players {
+		bands[m.config.LevelBand(p.Level)]++
+	}
+
+	m.metrics.QueuePlayers(bands)
+}
+
+// observeTick records duration of a matcher pass
+func (m *Service) observeTick(start time.Time) {
+	m.metrics.TickDuration(time.Since(start))
+}
+
+// levelSpread returns the difference between the highest and the lowest level
+func levelSpread(players []Player) int {
+	if len(players) == 0 {
+		return 0
+	}
+
+	lowest, highest := players[0].Level, players[0].Level
+	for _, p := range players[1:] {
+		lowest = min(lowest, p.Level)
+		highest = max(highest, p.Level)
+	}
+
+	return highest - lowest
+}
@@ -0,0 +1,141 @@
+package matchmaking
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+type Matcher = string
+
+const (
+	// MatcherGreedy groups every player with the next players within the level range
+	MatcherGreedy Matcher = "greedy"
+	// MatcherOptimal forms the most groups with the least level spread on every tick, preferring long waiters
+	MatcherOptimal Matcher = "optimal"
+)
+
+// optimalPlan is the best grouping of a prefix of the players
+type optimalPlan struct {
+	groups int
+	cost   float64
+	// prev is the length of the prefix the plan extends with the group, if any
+	prev  int
+	group []int
+}
+
+// better reports whether the plan forms more groups, or as many groups at a lower cost
+func (p optimalPlan) better(other optimalPlan) bool {
+	return p.groups > other.groups || p.groups == other.groups && p.cost < other.cost
+}
+
+// optimalGroups groups players sorted by level in passes until a pass finds no group,
+// every pass groups the players left out by the previous one
+func (m *Service) optimalGroups(players []StoredPlayer, now time.Time) [][]StoredPlayer {
+	var groups [][]StoredPlayer
+	for len(players) >= max(m.config.MinGroupSize, 1) {
+		found, rest := m.optimalPass(players, now)
+		if len(found) == 0 {
+			break
+		}
+		groups = append(groups, found...)
+		players = rest
+	}
+
+	return groups
+}
+
+// optimalPass finds the most groups of the lowest total cost, a group is taken from a window of up to
+// twice the group size ending at its highest level player. Without blocked players and recent opponents
+// the groups are optimal, otherwise players skipped in a window are left for the next pass.
+// Returns the groups and the players left out.
+func (m *Service) optimalPass(players []StoredPlayer, now time.Time) ([][]StoredPlayer, []StoredPlayer) {
+	size := max(m.config.MinGroupSize, 1)
+	plans := make([]optimalPlan, len(players)+1)
+	for i := 1; i <= len(players); i++ {
+		// the player at i-1 is left out
+		plans[i] = optimalPlan{groups: plans[i-1].groups, cost: plans[i-1].cost, prev: i - 1}
+		for start := i - size; start >= max(i-2*size, 0); start-- {
+			if players[i-1].Level-players[start].Level > m.config.MaxLevelDiff {
+				break
+			}
+			group, cost, ok := m.windowGroup(players[start:i], start, now)
+			if !ok {
+				continue
+			}
+			plan := optimalPlan{groups: plans[start].groups + 1, cost: plans[start].cost + cost, prev: start, group: group}
+			if plan.better(plans[i]) {
+				plans[i] = plan
+			}
+		}
+	}
+
+	var groups [][]StoredPlayer
+	grouped := make([]bool, len(players))
+	for i := len(players); i > 0; i = plans[i].prev {
+		if plans[i].group == nil {
+			continue
+		}
+		group := make([]StoredPlayer, 0, size)
+		for _, index := range plans[i].group {
+			group = append(group, players[index])
+			grouped[index] = true
+		}
+		groups = append(groups, group)
+	}
+	slices.Reverse(groups)
+
+	var rest []StoredPlayer
+	for i, p := range players {
+		if !grouped[i] {
+			rest = append(rest, p)
+		}
+	}
+
+	return groups, rest
+}
+
+// windowGroup forms a group of the last player of the window, the anchor, with the best scored players.
+// Returns indexes of the players offset by the window start and the cost of the group: its weighted level spread
+// and recent opponent penalties less the weighted wait time of its players.
+func (m *Service) windowGroup(window []StoredPlayer, offset int, now time.Time) ([]int, float64, bool) {
+	size := max(m.config.MinGroupSize, 1)
+	anchor := window[len(window)-1]
+	candidates := make([]int, len(window)-1)
+	for i := range candidates {
+		candidates[i] = i
+	}
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		return cmp.Compare(m.candidateScore(anchor, window[a], now), m.candidateScore(anchor, window[b], now))
+	})
+
+	group := append(make([]StoredPlayer, 0, size), anchor)
+	indexes := append(make([]int, 0, size), offset+len(window)-1)
+	lowest := anchor.Level
+	penalty := 0
+	for _, c := range candidates {
+		if len(group) == size {
+			break
+		}
+		p := window[c]
+		cost, ok := m.groupCost(group, p, now)
+		if !ok || anchor.Level-p.Level+cost > m.config.MaxLevelDiff {
+			continue
+		}
+		group = append(group, p)
+		indexes = append(indexes, offset+c)
+		lowest = min(lowest, p.Level)
+		penalty += cost
+	}
+	if len(group) < size {
+		return nil, 0, false
+	}
+
+	waited := 0.0
+	for _, p := range group {
+		waited += now.Sub(p.Created).Seconds()
+	}
+	slices.Sort(indexes)
+
+	return indexes, m.config.MatchLevelWeight*float64(anchor.Level-lowest+penalty) - m.config.MatchWaitWeight*waited, true
+}
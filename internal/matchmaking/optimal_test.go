@@ -0,0 +1,163 @@
+package matchmaking
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"matchmaking/internal/metrics"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newMatcherTestService(groupSize, levelDiff int, waitWeight float64) *Service {
+	return NewService(emptyLogger, MatchmakingConfig{
+		MinGroupSize:     groupSize,
+		MaxLevelDiff:     levelDiff,
+		Matcher:          MatcherOptimal,
+		MatchLevelWeight: 1,
+		MatchWaitWeight:  waitWeight,
+	}, NewStorage(), metrics.Noop{})
+}
+
+func storedPlayers(now time.Time, levels ...int) []StoredPlayer {
+	players := make([]StoredPlayer, 0, len(levels))
+	for i, level := range levels {
+		players = append(players, StoredPlayer{Player: Player{ID: fmt.Sprint(i + 1), Level: level}, Created: now})
+	}
+	return players
+}
+
+// totalSpread sums level spreads of the groups
+func totalSpread(groups [][]StoredPlayer) int {
+	spread := 0
+	for _, group := range groups {
+		levels := make([]int, 0, len(group))
+		for _, p := range group {
+			levels = append(levels, p.Level)
+		}
+		spread += slices.Max(levels) - slices.Min(levels)
+	}
+	return spread
+}
+
+func groupIDs(groups [][]StoredPlayer) [][]string {
+	ids := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		groupIDs := make([]string, 0, len(group))
+		for _, p := range group {
+			groupIDs = append(groupIDs, p.ID)
+		}
+		slices.Sort(groupIDs)
+		ids = append(ids, groupIDs)
+	}
+	return ids
+}
+
+func TestOptimalMatcherLessSpread(t *testing.T) {
+	// Arrange
+	service := newMatcherTestService(2, 1, 0)
+	players := storedPlayers(time.Now(), 1, 1, 2, 3, 3)
+
+	// Act
+	greedy := service.greedyGroups(players, time.Now())
+	optimal := service.optimalGroups(players, time.Now())
+
+	// Assert
+	assert.Len(t, greedy, 2)
+	assert.Equal(t, 1, totalSpread(greedy))
+	assert.Equal(t, [][]string{{"1", "2"}, {"4", "5"}}, groupIDs(optimal))
+	assert.Equal(t, 0, totalSpread(optimal))
+}
+
+func TestOptimalMatcherGroupsSkippedPlayers(t *testing.T) {
+	// Arrange
+	service := newMatcherTestService(2, 0, 0)
+	_, err := service.BlockList().Block("1", "2")
+	require.NoError(t, err)
+	players := storedPlayers(time.Now(), 5, 5, 5, 5)
+
+	// Act
+	greedy := service.greedyGroups(players, time.Now())
+	optimal := service.optimalGroups(players, time.Now())
+
+	// Assert
+	assert.Len(t, greedy, 2)
+	assert.Len(t, optimal, 2)
+	for _, group := range append(groupIDs(greedy), groupIDs(optimal)...) {
+		assert.NotEqual(t, []string{"1", "2"}, group)
+	}
+}
+
+func TestOptimalMatcherPrefersLongWaiters(t *testing.T) {
+	// Arrange
+	service := newMatcherTestService(2, 2, 0.1)
+	now := time.Now()
+	players := storedPlayers(now, 1, 2, 3)
+	players[0].Created = now.Add(-50 * time.Second)
+	players[2].Created = now.Add(-50 * time.Second)
+
+	// Act
+	greedy := service.greedyGroups(players, now)
+	optimal := service.optimalGroups(players, now)
+
+	// Assert
+	assert.Equal(t, [][]string{{"1", "3"}}, groupIDs(greedy))
+	assert.Equal(t, [][]string{{"1", "3"}}, groupIDs(optimal))
+}
+
+func TestOptimalMatcherMoreGroups(t *testing.T) {
+	// Arrange
+	service := newMatcherTestService(2, 1, 0.1)
+	now := time.Now()
+	players := storedPlayers(now, 1, 2, 3, 4)
+	players[1].Created = now.Add(-50 * time.Second)
+	players[2].Created = now.Add(-40 * time.Second)
+
+	// Act
+	greedy := service.greedyGroups(players, now)
+	optimal := service.optimalGroups(players, now)
+
+	// Assert
+	// long waiters in the middle do not take the players the level order pairs with the tail
+	assert.Equal(t, [][]string{{"1", "2"}, {"3", "4"}}, groupIDs(greedy))
+	assert.Equal(t, [][]string{{"1", "2"}, {"3", "4"}}, groupIDs(optimal))
+}
+
+func TestOptimalMatcherNotWorseThanGreedy(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	for run := range 50 {
+		t.Run(fmt.Sprint(run), func(t *testing.T) {
+			// Arrange
+			groupSize := 2 + random.IntN(4)
+			levelDiff := random.IntN(6)
+			service := newMatcherTestService(groupSize, levelDiff, 0)
+			levels := make([]int, 10+random.IntN(40))
+			for i := range levels {
+				levels[i] = random.IntN(50)
+			}
+			slices.Sort(levels)
+			players := storedPlayers(time.Now(), levels...)
+
+			// Act
+			greedy := service.greedyGroups(players, time.Now())
+			optimal := service.optimalGroups(players, time.Now())
+
+			// Assert
+			assert.GreaterOrEqual(t, len(optimal), len(greedy))
+			if len(optimal) == len(greedy) {
+				assert.LessOrEqual(t, totalSpread(optimal), totalSpread(greedy))
+			}
+			seen := map[string]bool{}
+			for _, group := range optimal {
+				assert.Len(t, group, groupSize)
+				assert.LessOrEqual(t, totalSpread([][]StoredPlayer{group}), levelDiff)
+				for _, p := range group {
+					assert.False(t, seen[p.ID], "player %s is in two groups", p.ID)
+					seen[p.ID] = true
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package matchmaking
+
+import "time"
+
+// pairCost returns the level distance added when both players are put into one group,
+// false when they may not be in one group
+func (m *Service) pairCost(a, b StoredPlayer, now time.Time) (int, bool) {
+	if m.blocked(a.Player, b.Player) && !m.blockFallback(a, b, now) {
+		return 0, false
+	}
+
+	return m.rematchCost(a.Player, b.Player, now)
+}
+
+// groupCost returns the level distance added when the candidate joins the group,
+// false when the candidate may not join it
+func (m *Service) groupCost(group []StoredPlayer, candidate StoredPlayer, now time.Time) (int, bool) {
+	cost := 0
+	for _, p := range group {
+		pairCost, ok := m.pairCost(p, candidate, now)
+		if !ok {
+			return 0, false
+		}
+		cost += pairCost
+	}
+
+	return cost, true
+}
@@ -0,0 +1,16 @@
+package matchmaking
+
+// matchQuality rates a match from 0 to 1 by its level spread, 1 when all players have the same level
+// and 0 when the spread reaches MAX_LEVEL_DIFF, the widest one the matcher forms
+func (m *Service) matchQuality(players []Player) float64 {
+	spread := levelSpread(players)
+	widest := m.config.MaxLevelDiff
+	if widest <= 0 {
+		if spread == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	return max(0, 1-float64(spread)/float64(widest))
+}
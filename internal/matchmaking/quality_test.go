@@ -0,0 +1,18 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+)
+
+func TestMatchQuality(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{MaxLevelDiff: 10}, NewStorage(), metrics.Noop{})
+
+	// Assert
+	assert.Equal(t, 1.0, service.matchQuality([]Player{{Level: 5}, {Level: 5}}))
+	assert.Equal(t, 0.5, service.matchQuality([]Player{{Level: 0}, {Level: 5}}))
+	assert.Equal(t, 0.0, service.matchQuality([]Player{{Level: 0}, {Level: 10}}))
+	assert.Equal(t, 0.0, service.matchQuality([]Player{{Level: 0}, {Level: 30}}))
+}
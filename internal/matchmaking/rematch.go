@@ -0,0 +1,118 @@
+package matchmaking
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+type RematchPolicy = string
+
+const (
+	// RematchOff matches players regardless of their recent opponents
+	RematchOff RematchPolicy = "off"
+	// RematchPenalty adds REMATCH_PENALTY_LEVELS to the level distance of every recent opponent in a group
+	RematchPenalty RematchPolicy = "penalty"
+	// RematchBlock never puts recent opponents into one group
+	RematchBlock RematchPolicy = "block"
+)
+
+// encounter is one match of a player with the IDs of the other players
+type encounter struct {
+	at        time.Time
+	opponents []string
+}
+
+// recentOpponents remembers with whom players were matched within the window,
+// only the last matches of a player count when last is set, then a zero window does not limit them
+type recentOpponents struct {
+	l          sync.RWMutex
+	window     time.Duration
+	last       int
+	encounters map[string][]encounter
+}
+
+func newRecentOpponents(config MatchmakingConfig) *recentOpponents {
+	return &recentOpponents{
+		window:     config.RematchWindow(),
+		last:       config.RematchLastMatches,
+		encounters: make(map[string][]encounter),
+	}
+}
+
+// record remembers the players as opponents of each other
+func (r *recentOpponents) record(players []Player, at time.Time) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	for _, p := range players {
+		opponents := make([]string, 0, len(players)-1)
+		for _, other := range players {
+			if other.ID != p.ID {
+				opponents = append(opponents, other.ID)
+			}
+		}
+
+		encounters := append(r.encounters[p.ID], encounter{at: at, opponents: opponents})
+		if r.last > 0 && len(encounters) > r.last {
+			encounters = slices.Delete(encounters, 0, len(encounters)-r.last)
+		}
+		r.encounters[p.ID] = encounters
+	}
+}
+
+// met reports whether either player has the other one among recent opponents
+func (r *recentOpponents) met(a, b string, now time.Time) bool {
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	return r.metLocked(a, b, now) || r.metLocked(b, a, now)
+}
+
+func (r *recentOpponents) metLocked(player, opponent string, now time.Time) bool {
+	for _, e := range r.encounters[player] {
+		if !r.expired(e, now) && slices.Contains(e.opponents, opponent) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rematchCost returns the level distance added for recent opponents, false when they may not be in one group
+func (m *Service) rematchCost(a, b Player, now time.Time) (int, bool) {
+	if !m.config.RematchEnabled() || !m.recent.met(a.ID, b.ID, now) {
+		return 0, true
+	}
+	if m.config.RematchPolicy == RematchBlock {
+		return 0, false
+	}
+
+	return m.config.RematchPenaltyLevels, true
+}
+
+// expired reports whether the encounter is older than the window
+func (r *recentOpponents) expired(e encounter, now time.Time) bool {
+	if r.window <= 0 && r.last > 0 {
+		return false
+	}
+
+	return now.Sub(e.at) > r.window
+}
+
+// prune forgets encounters older than the window
+func (r *recentOpponents) prune(now time.Time) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	for id, encounters := range r.encounters {
+		encounters = slices.DeleteFunc(encounters, func(e encounter) bool {
+			return r.expired(e, now)
+		})
+		if len(encounters) == 0 {
+			delete(r.encounters, id)
+			continue
+		}
+		r.encounters[id] = encounters
+	}
+}
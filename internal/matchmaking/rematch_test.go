@@ -0,0 +1,103 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+	"time"
+)
+
+func TestRecentOpponentsWindow(t *testing.T) {
+	// Arrange
+	recent := newRecentOpponents(MatchmakingConfig{RematchWindowSeconds: 60})
+	now := time.Now()
+
+	// Act
+	recent.record([]Player{{ID: "1"}, {ID: "2"}}, now)
+
+	// Assert
+	assert.True(t, recent.met("1", "2", now.Add(time.Minute)))
+	assert.True(t, recent.met("2", "1", now.Add(time.Minute)))
+	assert.False(t, recent.met("1", "3", now))
+	assert.False(t, recent.met("1", "2", now.Add(time.Minute+time.Second)))
+
+	recent.prune(now.Add(time.Minute + time.Second))
+	assert.Empty(t, recent.encounters)
+}
+
+func TestRecentOpponentsLastMatches(t *testing.T) {
+	// Arrange
+	recent := newRecentOpponents(MatchmakingConfig{RematchWindowSeconds: 60, RematchLastMatches: 1})
+	now := time.Now()
+
+	// Act
+	recent.record([]Player{{ID: "1"}, {ID: "2"}}, now)
+	recent.record([]Player{{ID: "1"}, {ID: "3"}}, now)
+
+	// Assert
+	assert.True(t, recent.met("1", "3", now))
+	// player 2 still remembers its only match
+	assert.True(t, recent.met("1", "2", now))
+	recent.record([]Player{{ID: "2"}, {ID: "4"}}, now)
+	assert.False(t, recent.met("1", "2", now))
+}
+
+func TestRecentOpponentsLastMatchesWithoutWindow(t *testing.T) {
+	// Arrange
+	recent := newRecentOpponents(MatchmakingConfig{RematchLastMatches: 1})
+	now := time.Now()
+	later := now.Add(24 * time.Hour)
+
+	// Act
+	recent.record([]Player{{ID: "1"}, {ID: "2"}}, now)
+	recent.prune(later)
+
+	// Assert
+	assert.True(t, recent.met("1", "2", later))
+	recent.record([]Player{{ID: "1"}, {ID: "3"}}, later)
+	recent.record([]Player{{ID: "2"}, {ID: "4"}}, later)
+	assert.False(t, recent.met("1", "2", later))
+	assert.True(t, recent.met("1", "3", later))
+}
+
+func TestFindMatchRematchPolicy(t *testing.T) {
+	players := []StoredPlayer{
+		{Player: Player{ID: "1", Level: 5}},
+		{Player: Player{ID: "2", Level: 5}},
+		{Player: Player{ID: "3", Level: 9}},
+	}
+	tests := []struct {
+		policy   RematchPolicy
+		penalty  int
+		expected []string
+	}{
+		{policy: RematchOff, expected: []string{"1", "2"}},
+		{policy: RematchBlock, expected: []string{"1", "3"}},
+		{policy: RematchPenalty, penalty: 5, expected: []string{"1", "2"}},
+		{policy: RematchPenalty, penalty: 10, expected: []string{"1", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			// Arrange
+			service := NewService(emptyLogger, MatchmakingConfig{
+				MinGroupSize:         2,
+				MaxLevelDiff:         5,
+				RematchPolicy:        tt.policy,
+				RematchWindowSeconds: 60,
+				RematchPenaltyLevels: tt.penalty,
+			}, NewStorage(), metrics.Noop{})
+			now := time.Now()
+			service.recent.record(toPlayers(players[:2]), now)
+
+			// Act
+			match := service.findMatch(players, players[0], nil, now)
+
+			// Assert
+			var ids []string
+			for _, p := range match {
+				ids = append(ids, p.ID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
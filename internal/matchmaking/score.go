@@ -0,0 +1,13 @@
+package matchmaking
+
+import (
+	"math"
+	"time"
+)
+
+// candidateScore scores the candidate for the group of the anchor, lower is better: the weighted level distance
+// less the weighted wait time, so players waiting long are preferred over slightly closer ones
+func (m *Service) candidateScore(anchor, candidate StoredPlayer, now time.Time) float64 {
+	distance := math.Abs(float64(candidate.Level - anchor.Level))
+	return m.config.MatchLevelWeight*distance - m.config.MatchWaitWeight*now.Sub(candidate.Created).Seconds()
+}
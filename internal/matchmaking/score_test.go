@@ -0,0 +1,90 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+	"time"
+)
+
+func TestGreedyMatcherAnchorsOldest(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{
+		MinGroupSize:     2,
+		MaxLevelDiff:     10,
+		MatchLevelWeight: 1,
+	}, NewStorage(), metrics.Noop{})
+	now := time.Now()
+	players := storedPlayers(now, 10, 10, 18)
+	players[2].Created = now.Add(-55 * time.Second)
+
+	// Act
+	groups := service.greedyGroups(players, now)
+
+	// Assert
+	// the old high level ticket is matched before the newer low level ones are matched together
+	assert.Equal(t, [][]string{{"1", "3"}}, groupIDs(groups))
+}
+
+func TestGreedyMatcherKeepsTailPlayers(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{
+		MinGroupSize:     2,
+		MaxLevelDiff:     2,
+		MatchLevelWeight: 1,
+		MatchWaitWeight:  0.1,
+	}, NewStorage(), metrics.Noop{})
+	now := time.Now()
+	players := storedPlayers(now, 1, 3, 5, 7)
+	players[1].Created = now.Add(-time.Minute)
+	players[2].Created = now.Add(-time.Minute)
+
+	// Act
+	groups := service.greedyGroups(players, now)
+
+	// Assert
+	assert.Equal(t, [][]string{{"1", "2"}, {"3", "4"}}, groupIDs(groups))
+}
+
+func TestGreedyMatcherPrefersLongWaiters(t *testing.T) {
+	tests := []struct {
+		name     string
+		waited   time.Duration
+		expected []string
+	}{
+		{name: "closer player", waited: 9 * time.Second, expected: []string{"1", "2"}},
+		{name: "long waiter", waited: 50 * time.Second, expected: []string{"1", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			service := NewService(emptyLogger, MatchmakingConfig{
+				MinGroupSize:     2,
+				MaxLevelDiff:     10,
+				MatchLevelWeight: 1,
+				MatchWaitWeight:  0.1,
+			}, NewStorage(), metrics.Noop{})
+			now := time.Now()
+			players := storedPlayers(now, 10, 11, 14)
+			players[0].Created = now.Add(-time.Minute)
+			players[2].Created = now.Add(-tt.waited)
+
+			// Act
+			groups := service.greedyGroups(players, now)
+
+			// Assert
+			assert.Equal(t, [][]string{tt.expected}, groupIDs(groups))
+		})
+	}
+}
+
+func TestCandidateScore(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{MatchLevelWeight: 2, MatchWaitWeight: 0.5}, NewStorage(), metrics.Noop{})
+	now := time.Now()
+	anchor := StoredPlayer{Player: Player{Level: 10}, Created: now}
+
+	// Assert
+	assert.Equal(t, 8.0, service.candidateScore(anchor, StoredPlayer{Player: Player{Level: 6}, Created: now}, now))
+	assert.Equal(t, 3.0, service.candidateScore(anchor, StoredPlayer{Player: Player{Level: 14}, Created: now.Add(-10 * time.Second)}, now))
+}
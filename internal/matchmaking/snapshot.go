@@ -0,0 +1,102 @@
+package matchmaking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// snapshot is the queue state kept between restarts
+type snapshot struct {
+	Players []StoredPlayer `json:"players"`
+	// Blocks are block lists by player
+	Blocks map[string][]string `json:"blocks,omitempty"`
+}
+
+// shutdown notifies waiting players that the service is shutting down and saves them with block lists
+// to the snapshot file, returns false when nobody is waiting
+func (m *Service) shutdown(qc queueCommand) (MatchSession, bool) {
+	players := m.storage.GetSortedByLevelPlayers()
+	if m.config.SnapshotFile != "" {
+		blocks := m.blocks.all()
+		if err := writeSnapshot(m.config.SnapshotFile, snapshot{Players: players, Blocks: blocks}); err != nil {
+			m.logger.Error("failed to save queue snapshot", slog.String("file", m.config.SnapshotFile), slog.String("error", err.Error()))
+		} else {
+			m.logger.Info("Queue snapshot saved", slog.String("file", m.config.SnapshotFile), slog.Int("players", len(players)), slog.Int("block_lists", len(blocks)))
+		}
+	}
+	endWaitSpans(qc, ChangesTypeShutdown, players)
+
+	if len(players) == 0 {
+		return MatchSession{}, false
+	}
+
+	return NewMatchSession(ChangesTypeShutdown, toPlayers(players)...).withSpanContext(qc.spanContext), true
+}
+
+// restoreSnapshot returns players saved on the previous shutdown to the queue keeping their wait time
+// and restores block lists
+func (m *Service) restoreSnapshot(ctx context.Context) {
+	if m.config.SnapshotFile == "" {
+		return
+	}
+
+	saved, err := readSnapshot(m.config.SnapshotFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		m.logger.ErrorContext(ctx, "failed to restore queue snapshot", slog.String("file", m.config.SnapshotFile), slog.String("error", err.Error()))
+		return
+	}
+
+	m.storage.AddPlayers(saved.Players)
+	m.blocks.restore(saved.Blocks)
+	if err := os.Remove(m.config.SnapshotFile); err != nil {
+		m.logger.WarnContext(ctx, "failed to remove queue snapshot", slog.String("file", m.config.SnapshotFile), slog.String("error", err.Error()))
+	}
+	m.logger.InfoContext(ctx, "Queue snapshot restored", slog.String("file", m.config.SnapshotFile), slog.Int("players", len(saved.Players)), slog.Int("block_lists", len(saved.Blocks)))
+}
+
+// writeSnapshot writes the snapshot to a temporary file and renames it, so a snapshot is never partially written
+func writeSnapshot(path string, saved snapshot) error {
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var saved snapshot
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return snapshot{}, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,96 @@
+package matchmaking
+
+import (
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStopNotifiesAndSavesWaitingPlayers(t *testing.T) {
+	// Arrange
+	config := MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             3,
+		FindGroupEverySeconds:    1,
+		MaxLevelDiff:             1,
+		MatchTimeoutAfterSeconds: 60,
+		SnapshotFile:             filepath.Join(t.TempDir(), "queue.json"),
+	}
+	service := NewService(emptyLogger, config, NewStorage(), metrics.Noop{})
+	output, _ := service.Start(t.Context())
+	assert.NoError(t, service.AddPlayer(Player{ID: "1", Level: 1}, Player{ID: "2", Level: 2}))
+	added := <-output
+
+	// Act
+	err := service.Stop(t.Context())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, ChangesTypeAdded, added.Type)
+	shutdown, ok := <-output
+	assert.True(t, ok)
+	assert.Equal(t, ChangesTypeShutdown, shutdown.Type)
+	assert.Len(t, shutdown.Players, 2)
+	_, ok = <-output
+	assert.False(t, ok, "output should be closed")
+	assert.ErrorIs(t, service.AddPlayer(Player{ID: "3"}), ErrNotAccepting)
+	assert.ErrorIs(t, service.RemovePlayer(Player{ID: "1"}), ErrStopped)
+	assert.ErrorIs(t, service.SetState(t.Context(), StateRunning), ErrStopped)
+	assert.FileExists(t, config.SnapshotFile)
+}
+
+func TestRunRestoresSnapshot(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), "queue.json")
+	created := time.Now().Add(-time.Minute).Round(time.Millisecond)
+	assert.NoError(t, writeSnapshot(file, snapshot{
+		Players: []StoredPlayer{{Player: Player{ID: "1", Level: 1}, Created: created}},
+		Blocks:  map[string][]string{"1": {"2"}},
+	}))
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MatchTimeoutAfterSeconds: 60,
+		SnapshotFile:             file,
+	}, NewStorage(), metrics.Noop{})
+
+	// Act
+	_, _ = service.Start(t.Context())
+
+	// Assert
+	player, ok := service.GetPlayer("1")
+	assert.True(t, ok)
+	assert.True(t, created.Equal(player.Created))
+	assert.Equal(t, []string{"2"}, service.BlockList().Get("1"))
+	_, err := os.Stat(file)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBlockListsSurviveRestart(t *testing.T) {
+	// Arrange
+	config := MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MatchTimeoutAfterSeconds: 60,
+		BlockListSize:            10,
+		SnapshotFile:             filepath.Join(t.TempDir(), "queue.json"),
+	}
+	first := NewService(emptyLogger, config, NewStorage(), metrics.Noop{})
+	_, _ = first.Start(t.Context())
+	_, err := first.BlockList().Block("1", "2", "3")
+	assert.NoError(t, err)
+	assert.NoError(t, first.Stop(t.Context()))
+	second := NewService(emptyLogger, config, NewStorage(), metrics.Noop{})
+
+	// Act
+	_, _ = second.Start(t.Context())
+
+	// Assert
+	assert.Equal(t, []string{"2", "3"}, second.BlockList().Get("1"))
+	assert.True(t, second.BlockList().Blocked("3", "1"))
+}
@@ -0,0 +1,101 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type ServiceState = string
+
+const (
+	// StateRunning accepts new players and forms matches
+	StateRunning ServiceState = "running"
+	// StatePausedAccepting accepts new players without forming matches
+	StatePausedAccepting ServiceState = "paused-accepting"
+	// StatePausedRejecting rejects new players without forming matches
+	StatePausedRejecting ServiceState = "paused-rejecting"
+	// StateDraining rejects new players and keeps forming matches for waiting players
+	StateDraining ServiceState = "draining"
+	// StateStopping rejects new players and forms no matches, the service is shutting down
+	StateStopping ServiceState = "stopping"
+)
+
+var (
+	// ErrUnknownState is returned when the state is not one of the service states
+	ErrUnknownState = errors.New("unknown service state")
+	// ErrNotAccepting is returned when the service does not accept new players in its state
+	ErrNotAccepting = errors.New("matchmaking queue does not accept new players")
+)
+
+// State returns the current state of the service.
+func (m *Service) State() ServiceState {
+	return m.state.Load().(ServiceState)
+}
+
+// SetState changes the state of the service and notifies waiting players about it.
+func (m *Service) SetState(ctx context.Context, state ServiceState) error {
+	switch state {
+	case StateRunning, StatePausedAccepting, StatePausedRejecting, StateDraining:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownState, state)
+	}
+
+	previous := m.State()
+	if previous == StateStopping {
+		return ErrStopped
+	}
+	if !m.state.CompareAndSwap(previous, state) {
+		return fmt.Errorf("state changed concurrently from %q", previous)
+	}
+	if previous == state {
+		return nil
+	}
+	m.logger.InfoContext(ctx, "Matchmaking state changed", slog.String("from", previous), slog.String("to", state))
+
+	qc := newQueueCommand(ctx, stateCommand)
+	qc.state = state
+
+	return m.enqueue(ctx, qc)
+}
+
+// Ready returns an error when the service does not accept new players or is unhealthy.
+func (m *Service) Ready() error {
+	if err := m.accepting(); err != nil {
+		return err
+	}
+
+	return m.Health().Err()
+}
+
+// accepting returns an error when new players may not join the queue in the current state
+func (m *Service) accepting() error {
+	if state := m.State(); !acceptsPlayers(state) {
+		return fmt.Errorf("%w: %s", ErrNotAccepting, state)
+	}
+
+	return nil
+}
+
+// acceptsPlayers reports whether new players may join the queue in the state
+func acceptsPlayers(state ServiceState) bool {
+	return state == StateRunning || state == StatePausedAccepting
+}
+
+// formsMatches reports whether the matcher forms matches in the state
+func formsMatches(state ServiceState) bool {
+	return state == StateRunning || state == StateDraining
+}
+
+// stateChangesType returns the status sent to waiting players when the service enters the state
+func stateChangesType(state ServiceState) PlayerChangesType {
+	switch state {
+	case StatePausedAccepting, StatePausedRejecting:
+		return ChangesTypePaused
+	case StateDraining:
+		return ChangesTypeDraining
+	default:
+		return ChangesTypeResumed
+	}
+}
@@ -0,0 +1,76 @@
+package matchmaking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestPausedQueueDoesNotMatch(t *testing.T) {
+	// Arrange
+	storage := NewStorage()
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MaxLevelDiff:             10,
+		MatchTimeoutAfterSeconds: 60,
+	}, storage, metrics.Noop{})
+	players := []Player{
+		{ID: "1", Level: 1},
+		{ID: "2", Level: 1},
+	}
+
+	// Act
+	synctest.Run(func() {
+		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*10)
+		defer cancelFunc()
+		output, _ := service.Start(ctx)
+		assert.NoError(t, service.SetState(ctx, StatePausedAccepting))
+		for _, p := range players {
+			assert.NoError(t, service.AddPlayer(p))
+		}
+
+		var types []PlayerChangesType
+		resumed := false
+		for match := range output {
+			types = append(types, match.Type)
+			if !resumed && storage.TotalPlayers() == len(players) {
+				time.Sleep(time.Second * 3)
+				assert.NoError(t, service.SetState(ctx, StateRunning))
+				resumed = true
+			}
+			if match.Type == ChangesTypeMatchFound {
+				cancelFunc()
+			}
+		}
+
+		// Assert
+		assert.Equal(t, []PlayerChangesType{
+			ChangesTypeAdded,
+			ChangesTypeAdded,
+			ChangesTypeResumed,
+			ChangesTypeMatchFound,
+		}, types)
+	})
+}
+
+func TestRejectingQueue(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{QueueSize: 10}, NewStorage(), metrics.Noop{})
+
+	// Act
+	errUnknown := service.SetState(t.Context(), "stopped")
+	errDraining := service.SetState(t.Context(), StateDraining)
+	errAdd := service.AddPlayer(Player{ID: "1"})
+
+	// Assert
+	assert.ErrorIs(t, errUnknown, ErrUnknownState)
+	assert.NoError(t, errDraining)
+	assert.ErrorIs(t, errAdd, ErrNotAccepting)
+	assert.ErrorIs(t, service.Ready(), ErrNotAccepting)
+	assert.Equal(t, StateDraining, service.State())
+}
@@ -1,6 +1,7 @@
 package matchmaking
 
 import (
+	"go.opentelemetry.io/otel/trace"
 	"slices"
 	"sort"
 	"sync"
@@ -9,7 +10,9 @@ import (
 
 type StoredPlayer struct {
 	Player
-	Created time.Time
+	Created time.Time `json:"created"`
+	// span measures time in queue
+	span trace.Span
 }
 
 // Storage represents a persistent storage or cache service before the data is stored in the database.
@@ -34,21 +37,23 @@ func (m *Storage) AddPlayers(players []StoredPlayer) {
 	m.sortPlayersByLevel()
 }
 
-// RemovePlayers removes players from the storage.
-func (m *Storage) RemovePlayers(players []StoredPlayer) []Player {
+// RemovePlayers removes players from the storage and returns the stored entries of removed players.
+func (m *Storage) RemovePlayers(players []StoredPlayer) []StoredPlayer {
 	m.l.Lock()
 	defer m.l.Unlock()
 
-	removedPlayers := make([]Player, 0, len(players))
+	removedPlayers := make([]StoredPlayer, 0, len(players))
 	for _, player := range players {
-		newPlayers := slices.DeleteFunc(m.players, func(p StoredPlayer) bool {
+		i := slices.IndexFunc(m.players, func(p StoredPlayer) bool {
 			return p.ID == player.ID
 		})
-		if len(newPlayers) == len(m.players) {
+		if i < 0 {
 			continue
 		}
-		removedPlayers = append(removedPlayers, player.Player)
-		m.players = newPlayers
+		removedPlayers = append(removedPlayers, m.players[i])
+		m.players = slices.DeleteFunc(m.players, func(p StoredPlayer) bool {
+			return p.ID == player.ID
+		})
 	}
 
 	m.sortPlayersByLevel()
@@ -66,6 +71,20 @@ func (m *Storage) GetSortedByLevelPlayers() []StoredPlayer {
 	return players
 }
 
+// GetPlayer returns the stored player by ID.
+func (m *Storage) GetPlayer(id string) (StoredPlayer, bool) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	i := slices.IndexFunc(m.players, func(p StoredPlayer) bool {
+		return p.ID == id
+	})
+	if i < 0 {
+		return StoredPlayer{}, false
+	}
+	return m.players[i], true
+}
+
 // TotalPlayers returns the total number of waiting players.
 func (m *Storage) TotalPlayers() int {
 	m.l.RLock()
@@ -74,6 +93,15 @@ func (m *Storage) TotalPlayers() int {
 	return len(m.players)
 }
 
+// toPlayers returns players without storage details.
+func toPlayers(storedPlayers []StoredPlayer) []Player {
+	players := make([]Player, 0, len(storedPlayers))
+	for _, p := range storedPlayers {
+		players = append(players, p.Player)
+	}
+	return players
+}
+
 // returns players by level.
 func (m *Storage) sortPlayersByLevel() {
 	sort.Slice(m.players, func(i, j int) bool {
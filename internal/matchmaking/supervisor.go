@@ -0,0 +1,107 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	// maxRestartBackoff limits the delay between restarts
+	maxRestartBackoff = 30 * time.Second
+	// restartResetAfter resets the restart counter of a loop which has been running that long
+	restartResetAfter = time.Minute
+)
+
+// ErrRestartsExhausted is returned by Err when a matchmaking goroutine kept panicking
+var ErrRestartsExhausted = errors.New("matchmaking restarts exhausted")
+
+// Err returns the error which stopped the service after the channel returned by Start is closed:
+// the cause of the canceled context, ErrRestartsExhausted, or nil when the service was stopped by Stop.
+func (m *Service) Err() error {
+	if err := m.err.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// supervise runs the loop until it returns, restarting it with exponential backoff after panics
+// and returns an error when RESTART_MAX restarts in a row are exhausted
+func (m *Service) supervise(ctx context.Context, component string, loop func(ctx context.Context)) error {
+	restarts := 0
+	for {
+		started := time.Now()
+		err := m.runRecovered(ctx, component, loop)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+
+		if time.Since(started) > restartResetAfter {
+			restarts = 0
+		}
+		if restarts >= m.config.RestartMax {
+			return fmt.Errorf("%w: %s failed after %d restarts: %w", ErrRestartsExhausted, component, restarts, err)
+		}
+		backoff := restartBackoff(m.config.RestartBackoff(), restarts)
+		restarts++
+		m.logger.WarnContext(ctx, "Restarting matchmaking goroutine",
+			slog.String("component", component),
+			slog.Int("restart", restarts),
+			slog.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// restartBackoff doubles the delay on every restart up to maxRestartBackoff
+func restartBackoff(base time.Duration, restarts int) time.Duration {
+	backoff := base
+	for range restarts {
+		if backoff >= maxRestartBackoff {
+			break
+		}
+		backoff *= 2
+	}
+
+	return min(backoff, maxRestartBackoff)
+}
+
+// runRecovered runs the loop, a panic is logged with the stack trace and returned as an error
+func (m *Service) runRecovered(ctx context.Context, component string, loop func(ctx context.Context)) (err error) {
+	beat := m.heartbeats[component]
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			beat.fail(err)
+			m.metrics.GoroutinePanicked(component)
+			m.logger.ErrorContext(ctx, "Matchmaking goroutine panicked",
+				slog.String("component", component),
+				slog.String("error", err.Error()),
+				slog.String("stack", string(debug.Stack())))
+		}
+	}()
+
+	beat.beat()
+	loop(ctx)
+
+	return nil
+}
+
+// terminate stops both loops with the error of exhausted restarts
+func (m *Service) terminate(err error, cancel context.CancelCauseFunc) {
+	if err == nil {
+		return
+	}
+
+	m.err.CompareAndSwap(nil, &err)
+	m.logger.Error("Matchmaking stopped", slog.String("error", err.Error()))
+	cancel(err)
+}
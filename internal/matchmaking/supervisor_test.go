@@ -0,0 +1,90 @@
+package matchmaking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"matchmaking/internal/metrics"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestSuperviseRestartsPanickedLoop(t *testing.T) {
+	synctest.Run(func() {
+		// Arrange
+		service := NewService(emptyLogger, MatchmakingConfig{
+			QueueSize:            10,
+			RestartMax:           3,
+			RestartBackoffMillis: 100,
+		}, NewStorage(), metrics.Noop{})
+		runs := 0
+		loop := func(context.Context) {
+			runs++
+			if runs < 3 {
+				panic("broken matcher")
+			}
+		}
+
+		// Act
+		err := service.supervise(t.Context(), componentMatcher, loop)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 3, runs)
+		assert.Equal(t, ComponentOk, service.Health().Components[componentMatcher].Status)
+	})
+}
+
+func TestSuperviseExhaustsRestarts(t *testing.T) {
+	synctest.Run(func() {
+		// Arrange
+		service := NewService(emptyLogger, MatchmakingConfig{
+			QueueSize:            10,
+			RestartMax:           2,
+			RestartBackoffMillis: 100,
+		}, NewStorage(), metrics.Noop{})
+		runs := 0
+		start := time.Now()
+
+		// Act
+		err := service.supervise(t.Context(), componentMatcher, func(context.Context) {
+			runs++
+			panic("broken matcher")
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, ErrRestartsExhausted)
+		assert.ErrorContains(t, err, "broken matcher")
+		assert.Equal(t, 3, runs)
+		assert.Equal(t, 300*time.Millisecond, time.Since(start), "backoff should double")
+		component := service.Health().Components[componentMatcher]
+		assert.Equal(t, ComponentFailed, component.Status)
+		assert.Contains(t, component.Error, "broken matcher")
+	})
+}
+
+func TestTerminateStoresError(t *testing.T) {
+	// Arrange
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:             10,
+		FindGroupEverySeconds: 1,
+	}, NewStorage(), metrics.Noop{})
+	ctx, cancel := context.WithCancelCause(t.Context())
+	defer cancel(nil)
+
+	// Act
+	service.terminate(ErrRestartsExhausted, cancel)
+
+	// Assert
+	assert.ErrorIs(t, service.Err(), ErrRestartsExhausted)
+	assert.ErrorIs(t, context.Cause(ctx), ErrRestartsExhausted)
+}
+
+func TestRestartBackoffIsCapped(t *testing.T) {
+	// Assert
+	assert.Equal(t, 500*time.Millisecond, restartBackoff(500*time.Millisecond, 0))
+	assert.Equal(t, 4*time.Second, restartBackoff(500*time.Millisecond, 3))
+	assert.Equal(t, maxRestartBackoff, restartBackoff(500*time.Millisecond, 40))
+	assert.Equal(t, maxRestartBackoff, restartBackoff(500*time.Millisecond, 1000))
+	assert.Equal(t, maxRestartBackoff, restartBackoff(time.Hour, 0))
+}
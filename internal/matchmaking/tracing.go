@@ -0,0 +1,83 @@
+package matchmaking
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("matchmaking/internal/matchmaking")
+
+var commandNames = map[playerCommand]string{
+	addPlayerCommand:     "add",
+	removePlayerCommand:  "remove",
+	timeoutPlayerCommand: "timeout",
+	createMatchCommand:   "match",
+	shutdownCommand:      "shutdown",
+	stateCommand:         "state",
+}
+
+// TraceContext returns ctx carrying the span context of the operation which produced the session.
+func (s MatchSession) TraceContext(ctx context.Context) context.Context {
+	if !s.spanContext.IsValid() {
+		return ctx
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, s.spanContext)
+}
+
+func (s MatchSession) withSpanContext(spanContext trace.SpanContext) MatchSession {
+	s.spanContext = spanContext
+	return s
+}
+
+// startEnqueueSpan measures how long the caller waits for a free slot in the command queue
+func startEnqueueSpan(ctx context.Context, command playerCommand, players []Player) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "matchmaking.enqueue", trace.WithAttributes(
+		attribute.String("matchmaking.command", commandNames[command]),
+		attribute.Int("matchmaking.players", len(players))))
+}
+
+// startMatchSpan marks formation of a match from the players
+func startMatchSpan(ctx context.Context, players []Player) (context.Context, trace.Span) {
+	ids := make([]string, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.ID)
+	}
+
+	return tracer.Start(ctx, "matchmaking.match", trace.WithAttributes(
+		attribute.StringSlice("matchmaking.player_ids", ids)))
+}
+
+// startWaitSpans starts time in queue spans of added players as children of the enqueue span
+func startWaitSpans(qc queueCommand, players []StoredPlayer) []StoredPlayer {
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), qc.spanContext)
+	for i := range players {
+		_, players[i].span = tracer.Start(ctx, "matchmaking.wait",
+			trace.WithTimestamp(players[i].Created),
+			trace.WithAttributes(
+				attribute.String("matchmaking.player_id", players[i].ID),
+				attribute.Int("matchmaking.level", players[i].Level)))
+	}
+
+	return players
+}
+
+// endWaitSpans ends time in queue spans of removed players linking them to the operation which removed them
+func endWaitSpans(qc queueCommand, reason PlayerChangesType, players []StoredPlayer) {
+	for _, p := range players {
+		if p.span == nil {
+			continue
+		}
+		if qc.spanContext.IsValid() {
+			p.span.AddLink(trace.Link{SpanContext: qc.spanContext})
+		}
+		p.span.SetAttributes(attribute.String("matchmaking.outcome", reason))
+		if reason == ChangesTypeTimeout {
+			p.span.SetStatus(codes.Error, "matchmaking timeout")
+		}
+		p.span.End()
+	}
+}
@@ -0,0 +1,63 @@
+package matchmaking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"matchmaking/internal/metrics"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestMatchSessionTraced(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	storage := NewStorage()
+	service := NewService(emptyLogger, MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MaxLevelDiff:             1,
+		MatchTimeoutAfterSeconds: 60,
+	}, storage, metrics.Noop{})
+
+	// Act
+	var matchSession MatchSession
+	synctest.Run(func() {
+		ctx, cancelFunc := context.WithTimeout(t.Context(), time.Second*5)
+		defer cancelFunc()
+		output, _ := service.Start(ctx)
+
+		service.AddPlayerContext(ctx, Player{ID: "1", Level: 1}, Player{ID: "2", Level: 2})
+
+		for match := range output {
+			if match.Type == ChangesTypeMatchFound {
+				matchSession = match
+				cancelFunc()
+			}
+		}
+	})
+
+	// Assert
+	spans := map[string]int{}
+	var matchSpanID string
+	for _, span := range recorder.Ended() {
+		spans[span.Name()]++
+		if span.Name() == "matchmaking.match" {
+			matchSpanID = span.SpanContext().SpanID().String()
+		}
+	}
+	assert.Equal(t, 1, spans["matchmaking.enqueue"])
+	assert.Equal(t, 2, spans["matchmaking.wait"])
+	assert.Equal(t, 1, spans["matchmaking.match"])
+	assert.GreaterOrEqual(t, spans["matchmaking.tick"], 1)
+	assert.True(t, matchSession.spanContext.IsValid())
+	assert.Equal(t, matchSpanID, matchSession.spanContext.SpanID().String())
+}
@@ -2,35 +2,281 @@ package metrics
 
 import (
 	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	"time"
 )
 
-var (
-	OnlinePlayers  prometheusclient.Counter
-	OfflinePlayers prometheusclient.Counter
-	TotalPlayers   *prometheusclient.GaugeVec
-)
+// Recorder records metrics of a matchmaking service and its server
+type Recorder interface {
+	// PlayerSubscribed player subscribed to status updates
+	PlayerSubscribed()
+	// PlayerUnsubscribed player unsubscribed from status updates
+	PlayerUnsubscribed()
+	// PlayersChanged players changed in queue by change type
+	PlayersChanged(changeType string, count int)
+	// QueuePlayers waiting players by level band, replacing previous bands
+	QueuePlayers(bands map[string]int)
+	// TimeToMatch time a player waited before a match was found
+	TimeToMatch(wait time.Duration)
+	// TimeToTimeout time a player waited before timed out
+	TimeToTimeout(wait time.Duration)
+	// MatchLevelSpread difference between the highest and the lowest level in a match
+	MatchLevelSpread(spread int)
+	// TickDuration duration of one matcher pass
+	TickDuration(duration time.Duration)
+	// CommandQueue usage of the command queue
+	CommandQueue(length, capacity int)
+	// GoroutinePanicked a matchmaking goroutine panicked and is restarted
+	GoroutinePanicked(component string)
+	// StatusDropped a status update was not delivered to a slow player because of the overflow policy
+	StatusDropped(policy string)
+	// AllocationFailed no game server was allocated for a found match
+	AllocationFailed()
+	// WebhookDelivery a webhook attempt finished with the result
+	WebhookDelivery(result string)
+	// EventPublishFailed an event was not published by the publisher
+	EventPublishFailed(publisher string)
+	// WatchEventDropped the oldest unacknowledged match event of the consumer was dropped to keep a new one
+	WatchEventDropped(consumer string)
+}
+
+// Noop recorder which drops all metrics, for library users without Prometheus
+type Noop struct{}
+
+var _ Recorder = Noop{}
+
+func (Noop) PlayerSubscribed()                 {}
+func (Noop) PlayerUnsubscribed()               {}
+func (Noop) PlayersChanged(string, int)        {}
+func (Noop) QueuePlayers(map[string]int)       {}
+func (Noop) TimeToMatch(time.Duration)         {}
+func (Noop) TimeToTimeout(time.Duration)       {}
+func (Noop) MatchLevelSpread(int)              {}
+func (Noop) TickDuration(time.Duration)        {}
+func (Noop) CommandQueue(length, capacity int) {}
+func (Noop) GoroutinePanicked(string)          {}
+func (Noop) StatusDropped(string)              {}
+func (Noop) AllocationFailed()                 {}
+func (Noop) WebhookDelivery(string)            {}
+func (Noop) EventPublishFailed(string)         {}
+func (Noop) WatchEventDropped(string)          {}
+
+// Prometheus recorder of one queue, every metric has a constant queue label
+type Prometheus struct {
+	online                 prometheusclient.Gauge
+	offline                prometheusclient.Counter
+	total                  *prometheusclient.CounterVec
+	queuePlayers           *prometheusclient.GaugeVec
+	timeToMatch            prometheusclient.Histogram
+	timeToTimeout          prometheusclient.Histogram
+	matchLevelSpread       prometheusclient.Histogram
+	tickDuration           prometheusclient.Histogram
+	commandQueueLength     prometheusclient.Gauge
+	commandQueueSaturation prometheusclient.Gauge
+	panics                 *prometheusclient.CounterVec
+	statusDropped          *prometheusclient.CounterVec
+	allocationFailures     prometheusclient.Counter
+	webhookDeliveries      *prometheusclient.CounterVec
+	eventPublishFailures   *prometheusclient.CounterVec
+	watchEventsDropped     *prometheusclient.CounterVec
+}
+
+var _ Recorder = (*Prometheus)(nil)
+
+// NewPrometheus creates metrics of the queue, they are collected after RegisterOn
+func NewPrometheus(queue string) *Prometheus {
+	labels := prometheusclient.Labels{"queue": queue}
+
+	return &Prometheus{
+		online: prometheusclient.NewGauge(prometheusclient.GaugeOpts{
+			Name:        "matchmaking_online",
+			Help:        "Number of players subscribed to status updates.",
+			ConstLabels: labels,
+		}),
+		offline: prometheusclient.NewCounter(prometheusclient.CounterOpts{
+			Name:        "matchmaking_offline",
+			Help:        "Total number of players unsubscribed from status updates.",
+			ConstLabels: labels,
+		}),
+		total: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name:        "matchmaking_total",
+			Help:        "Total number of player changes in the matchmaking service by type.",
+			ConstLabels: labels,
+		}, []string{"type"}),
+		queuePlayers: prometheusclient.NewGaugeVec(prometheusclient.GaugeOpts{
+			Name:        "matchmaking_queue_players",
+			Help:        "Number of players waiting in queue by level band.",
+			ConstLabels: labels,
+		}, []string{"band"}),
+		timeToMatch: prometheusclient.NewHistogram(prometheusclient.HistogramOpts{
+			Name:        "matchmaking_time_to_match_seconds",
+			Help:        "Time players waited in queue before a match was found.",
+			Buckets:     []float64{0.5, 1, 2, 5, 10, 15, 20, 30, 45, 60, 90, 120},
+			ConstLabels: labels,
+		}),
+		timeToTimeout: prometheusclient.NewHistogram(prometheusclient.HistogramOpts{
+			Name:        "matchmaking_time_to_timeout_seconds",
+			Help:        "Time players waited in queue before they timed out.",
+			Buckets:     []float64{10, 30, 60, 90, 120, 300, 600},
+			ConstLabels: labels,
+		}),
+		matchLevelSpread: prometheusclient.NewHistogram(prometheusclient.HistogramOpts{
+			Name:        "matchmaking_match_level_spread",
+			Help:        "Difference between the highest and the lowest player level in a match.",
+			Buckets:     []float64{0, 1, 2, 3, 5, 8, 13, 21, 34, 55},
+			ConstLabels: labels,
+		}),
+		tickDuration: prometheusclient.NewHistogram(prometheusclient.HistogramOpts{
+			Name:        "matchmaking_tick_duration_seconds",
+			Help:        "Duration of one matcher pass over waiting players.",
+			Buckets:     prometheusclient.ExponentialBuckets(0.0001, 4, 10),
+			ConstLabels: labels,
+		}),
+		commandQueueLength: prometheusclient.NewGauge(prometheusclient.GaugeOpts{
+			Name:        "matchmaking_command_queue_length",
+			Help:        "Number of commands waiting to be processed.",
+			ConstLabels: labels,
+		}),
+		commandQueueSaturation: prometheusclient.NewGauge(prometheusclient.GaugeOpts{
+			Name:        "matchmaking_command_queue_saturation",
+			Help:        "Ratio of the command queue capacity in use, from 0 to 1.",
+			ConstLabels: labels,
+		}),
+		panics: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name:        "matchmaking_goroutine_panics_total",
+			Help:        "Total number of panics recovered in matchmaking goroutines by component.",
+			ConstLabels: labels,
+		}, []string{"component"}),
+		statusDropped: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name:        "matchmaking_status_dropped_total",
+			Help:        "Total number of status updates not delivered to slow players by overflow policy.",
+			ConstLabels: labels,
+		}, []string{"policy"}),
+		allocationFailures: prometheusclient.NewCounter(prometheusclient.CounterOpts{
+			Name:        "matchmaking_allocation_failures_total",
+			Help:        "Total number of found matches without an allocated game server.",
+			ConstLabels: labels,
+		}),
+		webhookDeliveries: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name:        "matchmaking_webhook_deliveries_total",
+			Help:        "Total number of webhook deliveries by result.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+		eventPublishFailures: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name:        "matchmaking_event_publish_failures_total",
+			Help:        "Total number of events not published by publisher.",
+			ConstLabels: labels,
+		}, []string{"publisher"}),
+		watchEventsDropped: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name:        "matchmaking_watch_events_dropped_total",
+			Help:        "Total number of unacknowledged match events dropped by consumer.",
+			ConstLabels: labels,
+		}, []string{"consumer"}),
+	}
+}
+
+func (p *Prometheus) collectors() []prometheusclient.Collector {
+	return []prometheusclient.Collector{
+		p.online,
+		p.offline,
+		p.total,
+		p.queuePlayers,
+		p.timeToMatch,
+		p.timeToTimeout,
+		p.matchLevelSpread,
+		p.tickDuration,
+		p.commandQueueLength,
+		p.commandQueueSaturation,
+		p.panics,
+		p.statusDropped,
+		p.allocationFailures,
+		p.webhookDeliveries,
+		p.eventPublishFailures,
+		p.watchEventsDropped,
+	}
+}
+
+// RegisterOn registers all metrics, already registered ones are unregistered on error
+func (p *Prometheus) RegisterOn(registerer prometheusclient.Registerer) error {
+	for _, collector := range p.collectors() {
+		if err := registerer.Register(collector); err != nil {
+			p.UnRegisterFrom(registerer)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnRegisterFrom unregisters all metrics
+func (p *Prometheus) UnRegisterFrom(registerer prometheusclient.Registerer) {
+	for _, collector := range p.collectors() {
+		registerer.Unregister(collector)
+	}
+}
+
+func (p *Prometheus) PlayerSubscribed() {
+	p.online.Inc()
+}
+
+func (p *Prometheus) PlayerUnsubscribed() {
+	p.online.Dec()
+	p.offline.Inc()
+}
+
+func (p *Prometheus) PlayersChanged(changeType string, count int) {
+	p.total.WithLabelValues(changeType).Add(float64(count))
+}
+
+func (p *Prometheus) QueuePlayers(bands map[string]int) {
+	p.queuePlayers.Reset()
+	for band, count := range bands {
+		p.queuePlayers.WithLabelValues(band).Set(float64(count))
+	}
+}
+
+func (p *Prometheus) TimeToMatch(wait time.Duration) {
+	p.timeToMatch.Observe(wait.Seconds())
+}
+
+func (p *Prometheus) TimeToTimeout(wait time.Duration) {
+	p.timeToTimeout.Observe(wait.Seconds())
+}
+
+func (p *Prometheus) MatchLevelSpread(spread int) {
+	p.matchLevelSpread.Observe(float64(spread))
+}
+
+func (p *Prometheus) TickDuration(duration time.Duration) {
+	p.tickDuration.Observe(duration.Seconds())
+}
+
+func (p *Prometheus) CommandQueue(length, capacity int) {
+	p.commandQueueLength.Set(float64(length))
+	if capacity > 0 {
+		p.commandQueueSaturation.Set(float64(length) / float64(capacity))
+	}
+}
+
+func (p *Prometheus) GoroutinePanicked(component string) {
+	p.panics.WithLabelValues(component).Inc()
+}
+
+func (p *Prometheus) StatusDropped(policy string) {
+	p.statusDropped.WithLabelValues(policy).Inc()
+}
+
+func (p *Prometheus) AllocationFailed() {
+	p.allocationFailures.Inc()
+}
+
+func (p *Prometheus) WebhookDelivery(result string) {
+	p.webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+func (p *Prometheus) EventPublishFailed(publisher string) {
+	p.eventPublishFailures.WithLabelValues(publisher).Inc()
+}
 
-func RegisterOn(registerer prometheusclient.Registerer) {
-	TotalPlayers = prometheusclient.NewGaugeVec(prometheusclient.GaugeOpts{
-		Name: "matchmaking_total",
-		Help: "Total number of players in the matchmaking service.",
-	}, []string{"type"})
-	OnlinePlayers = prometheusclient.NewCounter(prometheusclient.CounterOpts{
-		Name: "matchmaking_online",
-		Help: "Total number of online players in the matchmaking service.",
-	})
-	OfflinePlayers = prometheusclient.NewCounter(prometheusclient.CounterOpts{
-		Name: "matchmaking_offline",
-		Help: "Total number of offline players in the matchmaking service.",
-	})
-
-	registerer.MustRegister(
-		TotalPlayers,
-		OnlinePlayers,
-		OfflinePlayers,
-	)
-}
-
-func UnRegisterFrom(registerer prometheusclient.Registerer) {
-	registerer.Unregister(TotalPlayers)
+func (p *Prometheus) WatchEventDropped(consumer string) {
+	p.watchEventsDropped.WithLabelValues(consumer).Inc()
 }
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrometheusQueuesShareRegistry(t *testing.T) {
+	// Arrange
+	registry := prometheusclient.NewRegistry()
+	first := NewPrometheus("first")
+	second := NewPrometheus("second")
+
+	// Act
+	errFirst := first.RegisterOn(registry)
+	errSecond := second.RegisterOn(registry)
+	errDuplicate := NewPrometheus("first").RegisterOn(registry)
+	first.PlayersChanged("added", 3)
+	second.PlayersChanged("added", 1)
+
+	// Assert
+	assert.NoError(t, errFirst)
+	assert.NoError(t, errSecond)
+	assert.Error(t, errDuplicate)
+	assert.Equal(t, 3.0, testutil.ToFloat64(first.total.WithLabelValues("added")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(second.total.WithLabelValues("added")))
+}
+
+func TestPrometheusUnRegisterFrom(t *testing.T) {
+	// Arrange
+	registry := prometheusclient.NewRegistry()
+	recorder := NewPrometheus("default")
+	assert.NoError(t, recorder.RegisterOn(registry))
+	recorder.QueuePlayers(map[string]int{"0-9": 2})
+
+	// Act
+	recorder.UnRegisterFrom(registry)
+	count, err := testutil.GatherAndCount(registry)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, NewPrometheus("default").RegisterOn(registry))
+}
+
+func TestPrometheusQueuePlayersReplacesBands(t *testing.T) {
+	// Arrange
+	recorder := NewPrometheus("default")
+	recorder.QueuePlayers(map[string]int{"0-9": 2, "10-19": 1})
+
+	// Act
+	recorder.QueuePlayers(map[string]int{"10-19": 4})
+
+	// Assert
+	assert.Equal(t, 1, testutil.CollectAndCount(recorder.queuePlayers))
+	assert.Equal(t, 4.0, testutil.ToFloat64(recorder.queuePlayers.WithLabelValues("10-19")))
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"matchmaking/pkg/auth"
+	"net/http"
+)
+
+// SetAuthenticator requires signed tokens from callers, players may act only on themselves
+// while callers with the backend role may act on behalf of any player
+func (s *MatchmakingServer) SetAuthenticator(authenticator *auth.Authenticator) *MatchmakingServer {
+	s.authenticator = authenticator
+	return s.SetPlayerAuthenticator(s.tokenPlayerAuthenticator)
+}
+
+// authorize checks that the caller may act on behalf of the players
+func (s *MatchmakingServer) authorize(ctx context.Context, playerIDs ...string) error {
+	if s.authenticator == nil {
+		return nil
+	}
+
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+	if claims.HasRole(s.config.BackendRole) {
+		return nil
+	}
+	for _, playerID := range playerIDs {
+		if playerID != claims.Subject {
+			return status.Errorf(codes.PermissionDenied, "caller %q may not act on behalf of player %q", claims.Subject, playerID)
+		}
+	}
+
+	return nil
+}
+
+// authorizeBackend checks that the caller has the backend role
+func (s *MatchmakingServer) authorizeBackend(ctx context.Context) error {
+	if s.authenticator == nil {
+		return nil
+	}
+
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "caller is not authenticated")
+	}
+	if !claims.HasRole(s.config.BackendRole) {
+		return status.Errorf(codes.PermissionDenied, "caller %q is not a backend", claims.Subject)
+	}
+
+	return nil
+}
+
+// tokenPlayerAuthenticator takes the player identity from the token subject,
+// backend callers choose the player with the player_id query parameter
+func (s *MatchmakingServer) tokenPlayerAuthenticator(request *http.Request) (string, error) {
+	claims, err := s.authenticator.VerifyRequest(request)
+	if err != nil {
+		return "", err
+	}
+
+	if playerID := request.URL.Query().Get("player_id"); playerID != "" && claims.HasRole(s.config.BackendRole) {
+		return playerID, nil
+	}
+
+	return claims.Subject, nil
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gen "matchmaking/generated/grpc"
+	"matchmaking/internal/matchmaking"
+)
+
+// BlockPlayers adds players to the block list of the player and returns the list
+func (s *MatchmakingServer) BlockPlayers(ctx context.Context, req *gen.BlockPlayersRequest) (*gen.BlockList, error) {
+	if err := s.authorizeBlockList(ctx, req.PlayerId); err != nil {
+		return nil, err
+	}
+	if len(req.PlayerIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no players provided")
+	}
+
+	blocked, err := s.service.BlockList().Block(req.PlayerId, req.PlayerIds...)
+	if errors.Is(err, matchmaking.ErrBlockListFull) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &gen.BlockList{PlayerId: req.PlayerId, PlayerIds: blocked}, nil
+}
+
+// UnblockPlayers removes players from the block list of the player and returns the list
+func (s *MatchmakingServer) UnblockPlayers(ctx context.Context, req *gen.UnblockPlayersRequest) (*gen.BlockList, error) {
+	if err := s.authorizeBlockList(ctx, req.PlayerId); err != nil {
+		return nil, err
+	}
+
+	blocked := s.service.BlockList().Unblock(req.PlayerId, req.PlayerIds...)
+	return &gen.BlockList{PlayerId: req.PlayerId, PlayerIds: blocked}, nil
+}
+
+// GetBlockList returns the block list of the player
+func (s *MatchmakingServer) GetBlockList(ctx context.Context, req *gen.GetBlockListRequest) (*gen.BlockList, error) {
+	if err := s.authorizeBlockList(ctx, req.PlayerId); err != nil {
+		return nil, err
+	}
+
+	return &gen.BlockList{PlayerId: req.PlayerId, PlayerIds: s.service.BlockList().Get(req.PlayerId)}, nil
+}
+
+// authorizeBlockList checks that the caller may change the block list of the player
+func (s *MatchmakingServer) authorizeBlockList(ctx context.Context, playerID string) error {
+	if playerID == "" {
+		return status.Error(codes.InvalidArgument, "player id is not provided")
+	}
+	if err := s.authorize(ctx, playerID); err != nil {
+		return err
+	}
+
+	return s.limit(ctx)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gen "matchmaking/generated/grpc"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/pkg/auth"
+	"testing"
+)
+
+// newBlocksTestClient returns a client of a server which authorizes callers
+func newBlocksTestClient(t *testing.T, blockListSize int) gen.MatchmakingClient {
+	authenticator, err := auth.NewAuthenticator(auth.AuthConfig{AuthHmacSecret: "secret"})
+	require.NoError(t, err)
+	server := newTestServer(MatchmakingServerConfig{BackendRole: "backend"}, matchmaking.MatchmakingConfig{
+		QueueSize:     10,
+		MinGroupSize:  2,
+		BlockListSize: blockListSize,
+	})
+	server.SetAuthenticator(authenticator)
+
+	return newTestGrpcClient(t, server)
+}
+
+func TestBlockPlayers(t *testing.T) {
+	// Arrange
+	client := newBlocksTestClient(t, 10)
+	ctx := withTestCaller(t.Context(), "player-1")
+
+	// Act
+	blocked, err := client.BlockPlayers(ctx, &gen.BlockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-3", "player-2"}})
+	require.NoError(t, err)
+	unblocked, err := client.UnblockPlayers(ctx, &gen.UnblockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-3"}})
+	require.NoError(t, err)
+	list, err := client.GetBlockList(ctx, &gen.GetBlockListRequest{PlayerId: "player-1"})
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"player-2", "player-3"}, blocked.PlayerIds)
+	assert.Equal(t, []string{"player-2"}, unblocked.PlayerIds)
+	assert.Equal(t, "player-1", list.PlayerId)
+	assert.Equal(t, []string{"player-2"}, list.PlayerIds)
+}
+
+func TestBlockPlayersErrors(t *testing.T) {
+	// Arrange
+	client := newBlocksTestClient(t, 1)
+	ctx := withTestCaller(t.Context(), "player-1")
+
+	// Act
+	_, noPlayersErr := client.BlockPlayers(ctx, &gen.BlockPlayersRequest{PlayerId: "player-1"})
+	_, noPlayerIdErr := client.GetBlockList(ctx, &gen.GetBlockListRequest{})
+	_, fullErr := client.BlockPlayers(ctx, &gen.BlockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-2", "player-3"}})
+
+	// Assert
+	assert.Equal(t, codes.InvalidArgument, status.Code(noPlayersErr))
+	assert.Equal(t, codes.InvalidArgument, status.Code(noPlayerIdErr))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(fullErr))
+}
+
+func TestBlockListAuthorization(t *testing.T) {
+	// Arrange
+	client := newBlocksTestClient(t, 10)
+	player := withTestCaller(t.Context(), "player-1")
+	other := withTestCaller(t.Context(), "player-2")
+	backend := withTestCaller(t.Context(), "game-backend", "backend")
+	_, err := client.BlockPlayers(player, &gen.BlockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-3"}})
+	require.NoError(t, err)
+
+	// Act
+	_, anonymousErr := client.GetBlockList(t.Context(), &gen.GetBlockListRequest{PlayerId: "player-1"})
+	_, getErr := client.GetBlockList(other, &gen.GetBlockListRequest{PlayerId: "player-1"})
+	_, blockErr := client.BlockPlayers(other, &gen.BlockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-4"}})
+	_, unblockErr := client.UnblockPlayers(other, &gen.UnblockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-3"}})
+	blocked, backendErr := client.BlockPlayers(backend, &gen.BlockPlayersRequest{PlayerId: "player-1", PlayerIds: []string{"player-4"}})
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(anonymousErr))
+	assert.Equal(t, codes.PermissionDenied, status.Code(getErr))
+	assert.Equal(t, codes.PermissionDenied, status.Code(blockErr))
+	assert.Equal(t, codes.PermissionDenied, status.Code(unblockErr))
+	require.NoError(t, backendErr)
+	assert.Equal(t, []string{"player-3", "player-4"}, blocked.PlayerIds)
+}
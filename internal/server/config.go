@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+type MatchmakingServerConfig struct {
+	WebSocketAllowedOrigins []string `env:"WEBSOCKET_ALLOWED_ORIGINS"`
+	BackendRole             string   `env:"AUTH_BACKEND_ROLE, default=backend"`
+	RateLimitCallerRps      float64  `env:"RATE_LIMIT_CALLER_RPS, default=5"`
+	RateLimitCallerBurst    int      `env:"RATE_LIMIT_CALLER_BURST, default=10"`
+	RateLimitPeerRps        float64  `env:"RATE_LIMIT_PEER_RPS, default=0"`
+	RateLimitPeerBurst      int      `env:"RATE_LIMIT_PEER_BURST, default=100"`
+	ShedQueuePercent        int      `env:"SHED_QUEUE_PERCENT, default=90"`
+	StatusQueueSize         int      `env:"STATUS_QUEUE_SIZE, default=16"`
+	StatusOverflowPolicy    string   `env:"STATUS_OVERFLOW_POLICY, default=drop-oldest"`
+	WatchAckTimeoutSeconds  int      `env:"WATCH_ACK_TIMEOUT_SECONDS, default=30"`
+	WatchPendingSize        int      `env:"WATCH_PENDING_SIZE, default=1000"`
+	WatchConsumerTTLSeconds int      `env:"WATCH_CONSUMER_TTL_SECONDS, default=600"`
+}
+
+func (c MatchmakingServerConfig) WatchAckTimeout() time.Duration {
+	return time.Duration(c.WatchAckTimeoutSeconds) * time.Second
+}
+
+func (c MatchmakingServerConfig) WatchConsumerTTL() time.Duration {
+	return time.Duration(c.WatchConsumerTTLSeconds) * time.Second
+}
+
+// Validate checks the settings which have no safe default
+func (c MatchmakingServerConfig) Validate() error {
+	switch c.StatusOverflowPolicy {
+	case OverflowDropOldest, OverflowDisconnect:
+	default:
+		return fmt.Errorf("unknown STATUS_OVERFLOW_POLICY %q, expected %q or %q", c.StatusOverflowPolicy, OverflowDropOldest, OverflowDisconnect)
+	}
+	if c.WatchAckTimeoutSeconds <= 0 {
+		return fmt.Errorf("WATCH_ACK_TIMEOUT_SECONDS must be positive, got %d", c.WatchAckTimeoutSeconds)
+	}
+
+	return nil
+}
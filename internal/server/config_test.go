@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MatchmakingServerConfig
+		valid  bool
+	}{
+		{name: "drop oldest", config: MatchmakingServerConfig{StatusOverflowPolicy: OverflowDropOldest, WatchAckTimeoutSeconds: 1}, valid: true},
+		{name: "disconnect", config: MatchmakingServerConfig{StatusOverflowPolicy: OverflowDisconnect, WatchAckTimeoutSeconds: 1}, valid: true},
+		{name: "unknown overflow policy", config: MatchmakingServerConfig{StatusOverflowPolicy: "drop-newest", WatchAckTimeoutSeconds: 1}},
+		{name: "zero ack timeout", config: MatchmakingServerConfig{StatusOverflowPolicy: OverflowDropOldest}},
+		{name: "negative ack timeout", config: MatchmakingServerConfig{StatusOverflowPolicy: OverflowDropOldest, WatchAckTimeoutSeconds: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.config.Validate()
+
+			// Assert
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
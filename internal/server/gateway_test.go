@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestGateway serves the HTTP/JSON gateway of a started service and returns the service and the gateway URL
+func newTestGateway(t *testing.T) (*matchmaking.Service, *MatchmakingServer, string) {
+	logger := slog.New(slog.DiscardHandler)
+	service := matchmaking.NewService(logger, matchmaking.MatchmakingConfig{
+		QueueSize:                10,
+		MinGroupSize:             2,
+		FindGroupEverySeconds:    1,
+		MatchTimeoutAfterSeconds: 60,
+	}, matchmaking.NewStorage(), metrics.Noop{})
+	server := NewMatchmakingServer(logger, MatchmakingServerConfig{
+		StatusQueueSize:      4,
+		StatusOverflowPolicy: OverflowDropOldest,
+	}, service, metrics.Noop{})
+	output, err := service.Start(t.Context())
+	require.NoError(t, err)
+	go func() { _ = server.RunStatusUpdater(t.Context(), output) }()
+
+	mux := runtime.NewServeMux()
+	require.NoError(t, server.RegisterGateway()(t.Context(), mux, newTestGrpcConn(t, server)))
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(server.Close)
+
+	return service, server, httpServer.URL
+}
+
+// readStatus reads the next status of the streamed status response
+func readStatus(t *testing.T, reader *bufio.Reader) map[string]any {
+	line, err := reader.ReadBytes('\n')
+	require.NoError(t, err)
+	var frame struct {
+		Result map[string]any `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &frame))
+	return frame.Result
+}
+
+func TestGatewayAddsAndRemovesPlayer(t *testing.T) {
+	// Arrange
+	service, server, url := newTestGateway(t)
+	// the gateway sends response headers with the first status
+	statusResponses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url + "/v1/players/player-1/status")
+		assert.NoError(t, err)
+		statusResponses <- resp
+	}()
+	require.Eventually(t, func() bool { return server.subscribed("player-1") }, time.Second, time.Millisecond)
+	post := func(path, body string) int {
+		resp, err := http.Post(url+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Act
+	addStatus := post("/v1/players", `{"players": [{"id": "player-1", "level": 5}]}`)
+	statusResp := <-statusResponses
+	require.NotNil(t, statusResp)
+	defer statusResp.Body.Close()
+	status := bufio.NewReader(statusResp.Body)
+	added := readStatus(t, status)
+	_, waiting := service.GetPlayer("player-1")
+	removeStatus := post("/v1/players:remove", `{"players": [{"id": "player-1", "level": 5}]}`)
+	removed := readStatus(t, status)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, addStatus)
+	assert.Equal(t, http.StatusOK, statusResp.StatusCode)
+	assert.Equal(t, matchmaking.ChangesTypeAdded, added["type"])
+	assert.NotEmpty(t, added["id"])
+	assert.True(t, waiting, "added player should wait in the queue")
+	assert.Equal(t, http.StatusOK, removeStatus)
+	assert.Equal(t, matchmaking.ChangesTypeRemoved, removed["type"])
+	_, waiting = service.GetPlayer("player-1")
+	assert.False(t, waiting, "removed player should leave the queue")
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	gen "matchmaking/generated/grpc"
+	"matchmaking/internal/history"
+)
+
+// SetHistory serves GetMatch and ListPlayerMatches from the store, they are unimplemented without it
+func (s *MatchmakingServer) SetHistory(store *history.Store) *MatchmakingServer {
+	s.history = store
+	return s
+}
+
+// GetMatch returns a found match, it is available to backend callers
+func (s *MatchmakingServer) GetMatch(ctx context.Context, req *gen.GetMatchRequest) (*gen.Match, error) {
+	if s.history == nil {
+		return nil, status.Error(codes.Unimplemented, "match history is disabled")
+	}
+	if err := s.authorizeBackend(ctx); err != nil {
+		return nil, err
+	}
+
+	match, err := s.history.Get(req.Id)
+	if errors.Is(err, history.ErrMatchNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return toMatch(match), nil
+}
+
+// ListPlayerMatches returns a page of found matches of the player, the newest first
+func (s *MatchmakingServer) ListPlayerMatches(ctx context.Context, req *gen.ListPlayerMatchesRequest) (*gen.ListPlayerMatchesResponse, error) {
+	if s.history == nil {
+		return nil, status.Error(codes.Unimplemented, "match history is disabled")
+	}
+	if req.PlayerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "player id is not provided")
+	}
+	if err := s.authorize(ctx, req.PlayerId); err != nil {
+		return nil, err
+	}
+
+	matches, nextPageToken, err := s.history.ListPlayerMatches(req.PlayerId, int(req.PageSize), req.PageToken)
+	if errors.Is(err, history.ErrInvalidPageToken) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &gen.ListPlayerMatchesResponse{
+		Matches:       make([]*gen.Match, 0, len(matches)),
+		NextPageToken: nextPageToken,
+	}
+	for _, match := range matches {
+		resp.Matches = append(resp.Matches, toMatch(match))
+	}
+
+	return resp, nil
+}
+
+func toMatch(match history.Match) *gen.Match {
+	resp := &gen.Match{
+		Id:      match.ID,
+		Queue:   match.Queue,
+		Created: timestamppb.New(match.Created),
+		Players: make([]*gen.PlayerData, 0, len(match.Players)),
+		Quality: match.Quality,
+	}
+	for _, p := range match.Players {
+		resp.Players = append(resp.Players, &gen.PlayerData{
+			Id:    p.ID,
+			Level: int32(p.Level),
+		})
+	}
+
+	return resp
+}
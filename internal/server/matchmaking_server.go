@@ -2,30 +2,66 @@ package server
 
 import (
 	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"log/slog"
 	gen "matchmaking/generated/grpc"
+	"matchmaking/generated/openapi"
+	"matchmaking/internal/history"
 	"matchmaking/internal/matchmaking"
 	"matchmaking/internal/metrics"
+	"matchmaking/pkg/auth"
+	"net/http"
 	"sync"
+	"time"
 )
 
+// statusSender delivers status updates to a subscribed player
+type statusSender interface {
+	Send(*gen.StatusResponse) error
+}
+
+var tracer = otel.Tracer("matchmaking/internal/server")
+
 type MatchmakingServer struct {
 	gen.UnimplementedMatchmakingServer
-	logger       *slog.Logger
-	service      *matchmaking.Service
-	playerStates map[string]grpc.ServerStreamingServer[gen.StatusResponse]
-	l            sync.RWMutex
+	logger        *slog.Logger
+	config        MatchmakingServerConfig
+	service       *matchmaking.Service
+	playerStates  map[string]*subscriber
+	watchers      *watchHub
+	history       *history.Store
+	upgrader      websocket.Upgrader
+	authenticate  PlayerAuthenticator
+	authenticator *auth.Authenticator
+	limiter       *rateLimiter
+	metrics       metrics.Recorder
+	// closing is closed when status streams must end after queued updates are sent
+	closing   chan struct{}
+	closeOnce sync.Once
+	l         sync.RWMutex
 }
 
-func NewMatchmakingServer(logger *slog.Logger, service *matchmaking.Service) *MatchmakingServer {
+func NewMatchmakingServer(logger *slog.Logger, config MatchmakingServerConfig, service *matchmaking.Service, recorder metrics.Recorder) *MatchmakingServer {
 	return &MatchmakingServer{
 		logger:       logger,
+		config:       config,
 		service:      service,
-		playerStates: make(map[string]grpc.ServerStreamingServer[gen.StatusResponse], 10),
+		playerStates: make(map[string]*subscriber, 10),
+		watchers:     newWatchHub(config),
+		upgrader:     newUpgrader(config),
+		authenticate: queryPlayerAuthenticator,
+		limiter:      newRateLimiter(config),
+		metrics:      recorder,
+		closing:      make(chan struct{}),
 	}
 }
 
@@ -35,28 +71,61 @@ func (s *MatchmakingServer) Register() func(registrar grpc.ServiceRegistrar) {
 	}
 }
 
-func (s *MatchmakingServer) AddPlayer(_ context.Context, req *gen.AddPlayerRequest) (*gen.AddPlayerResponse, error) {
+func (s *MatchmakingServer) RegisterGateway() func(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	return gen.RegisterMatchmakingHandler
+}
+
+// OpenAPI serves the OpenAPI document of the HTTP/JSON gateway
+func (s *MatchmakingServer) OpenAPI(writer http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(openapi.Spec)
+}
+
+func (s *MatchmakingServer) AddPlayer(ctx context.Context, req *gen.AddPlayerRequest) (*gen.AddPlayerResponse, error) {
 	if len(req.Players) == 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "no players provided")
 	}
+	if err := s.authorize(ctx, playerIDs(req.Players)...); err != nil {
+		return nil, err
+	}
+	if err := s.limit(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.shed(ctx); err != nil {
+		return nil, err
+	}
 
 	players := make([]matchmaking.Player, 0, len(req.Players))
 	for _, p := range req.Players {
 		players = append(players, matchmaking.Player{
 			ID:    p.Id,
 			Level: int(p.Level),
+			Avoid: p.Avoid,
 		})
 	}
 
-	s.service.AddPlayer(players...)
+	err := s.service.AddPlayerContext(ctx, players...)
+	if errors.Is(err, matchmaking.ErrAvoidListTooLong) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 
 	return &gen.AddPlayerResponse{}, nil
 }
 
-func (s *MatchmakingServer) RemovePlayer(_ context.Context, req *gen.RemovePlayerRequest) (*gen.RemovePlayerResponse, error) {
+func (s *MatchmakingServer) RemovePlayer(ctx context.Context, req *gen.RemovePlayerRequest) (*gen.RemovePlayerResponse, error) {
 	if len(req.Players) == 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "no players provided")
 	}
+	if err := s.authorize(ctx, playerIDs(req.Players)...); err != nil {
+		return nil, err
+	}
+	if err := s.limit(ctx); err != nil {
+		return nil, err
+	}
 
 	players := make([]matchmaking.Player, 0, len(req.Players))
 	for _, p := range req.Players {
@@ -66,7 +135,9 @@ func (s *MatchmakingServer) RemovePlayer(_ context.Context, req *gen.RemovePlaye
 		})
 	}
 
-	s.service.RemovePlayer(players...)
+	if err := s.service.RemovePlayerContext(ctx, players...); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 
 	return &gen.RemovePlayerResponse{}, nil
 }
@@ -74,61 +145,217 @@ func (s *MatchmakingServer) RemovePlayer(_ context.Context, req *gen.RemovePlaye
 func (s *MatchmakingServer) Status(req *gen.StatusRequest, stream grpc.ServerStreamingServer[gen.StatusResponse]) error {
 	s.logger.Debug("Status request", slog.Any("request", req))
 
-	// TODO: check if player exists and authenticated
+	if err := s.authorize(stream.Context(), req.PlayerId); err != nil {
+		return err
+	}
+
+	// TODO: check if player exists
+	sub := s.subscribe(req.PlayerId, stream)
+	defer s.unsubscribe(sub)
+
+	select {
+	case <-stream.Context().Done():
+		return nil
+	case <-sub.done:
+		switch {
+		case errors.Is(sub.err, errServerClosing):
+			return status.Error(codes.Unavailable, sub.err.Error())
+		case errors.Is(sub.err, errStatusOverflow):
+			return status.Error(codes.ResourceExhausted, sub.err.Error())
+		default:
+			return sub.err
+		}
+	}
+}
+
+// WatchMatches streams match events of all players to a backend consumer,
+// events are sent again until the consumer acknowledges them with AckMatches
+func (s *MatchmakingServer) WatchMatches(req *gen.WatchMatchesRequest, stream grpc.ServerStreamingServer[gen.MatchEvent]) error {
+	s.logger.Debug("Watch matches request", slog.Any("request", req))
+
+	if err := s.authorizeBackend(stream.Context()); err != nil {
+		return err
+	}
+	if req.Consumer == "" {
+		return status.Error(codes.InvalidArgument, "consumer is not provided")
+	}
+
+	watch := s.watchers.watch(req.Consumer, req.Queue, req.Types)
+	defer s.watchers.release(watch)
+
+	timer := time.NewTimer(s.watchers.ackTimeout)
+	defer timer.Stop()
+	for {
+		wait, err := s.sendDueEvents(watch, stream)
+		if err != nil {
+			return err
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-watch.replaced:
+			return status.Error(codes.Aborted, "consumer is watched by another stream")
+		case <-s.closing:
+			// send events published since the last pass, the status updater is flushed before closing
+			if _, err := s.sendDueEvents(watch, stream); err != nil {
+				return err
+			}
+			return status.Error(codes.Unavailable, errServerClosing.Error())
+		case <-watch.notify:
+		case <-timer.C:
+		}
+	}
+}
+
+// sendDueEvents sends events which are not sent yet or not acknowledged in time,
+// returns the time to wait before the next redelivery
+func (s *MatchmakingServer) sendDueEvents(watch *watchStream, stream grpc.ServerStreamingServer[gen.MatchEvent]) (time.Duration, error) {
+	events, wait := s.watchers.due(watch, time.Now())
+	for _, event := range events {
+		if err := stream.Send(event); err != nil {
+			return 0, err
+		}
+	}
+
+	return wait, nil
+}
+
+// AckMatches acknowledges match events of the consumer, they are not sent again
+func (s *MatchmakingServer) AckMatches(ctx context.Context, req *gen.AckMatchesRequest) (*gen.AckMatchesResponse, error) {
+	if err := s.authorizeBackend(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := s.watchers.ack(req.Consumer, req.Ids); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &gen.AckMatchesResponse{}, nil
+}
+
+// Close ends status streams of all subscribed players, call it after the status updater is flushed
+func (s *MatchmakingServer) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+}
+
+// subscribe registers a sender for status updates of the player, replacing the previous one
+func (s *MatchmakingServer) subscribe(playerID string, sender statusSender) *subscriber {
+	sub := newSubscriber(playerID, sender, s.config.StatusQueueSize, s.config.StatusOverflowPolicy, s.closing)
+	go sub.run(s.logger)
+
 	s.l.Lock()
-	s.playerStates[req.PlayerId] = stream
+	s.playerStates[playerID] = sub
 	s.l.Unlock()
-	metrics.OnlinePlayers.Inc()
-	defer func() {
-		s.l.Lock()
-		delete(s.playerStates, req.PlayerId)
-		s.l.Unlock()
-		metrics.OfflinePlayers.Inc()
-	}()
+	s.metrics.PlayerSubscribed()
 
-	<-stream.Context().Done()
+	return sub
+}
 
-	return nil
+// unsubscribe removes the subscriber unless the player has already subscribed with another one
+// and waits until it stops sending
+func (s *MatchmakingServer) unsubscribe(sub *subscriber) {
+	s.l.Lock()
+	if s.playerStates[sub.playerID] == sub {
+		delete(s.playerStates, sub.playerID)
+	}
+	s.l.Unlock()
+	sub.close()
+	s.metrics.PlayerUnsubscribed()
 }
 
-// RunStatusUpdater sends status updates to players
+// RunStatusUpdater sends status updates to players until the output of the service is closed,
+// returns the error which stopped the service
 func (s *MatchmakingServer) RunStatusUpdater(ctx context.Context, outputStatus <-chan matchmaking.MatchSession) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case match := <-outputStatus:
-			metrics.TotalPlayers.WithLabelValues(match.Type).Add(float64(len(match.Players)))
+		case match, ok := <-outputStatus:
+			if !ok {
+				return s.service.Err()
+			}
 			s.logger.DebugContext(ctx, "Player status updater:", slog.String("type", match.Type), slog.Any("players", match.Players))
 
-			for _, player := range match.Players {
-				s.l.RLock()
-				stream, ok := s.playerStates[player.ID]
-				s.l.RUnlock()
-				if ok {
-					resp := &gen.StatusResponse{
-						Id:      match.ID,
-						Created: timestamppb.New(match.Created),
-						Type:    match.Type,
-					}
-					if match.Type == matchmaking.ChangesTypeMatchFound {
-						resp.Players = make([]*gen.PlayerData, 0, len(match.Players))
-						for _, p := range match.Players {
-							resp.Players = append(resp.Players, &gen.PlayerData{
-								Id:    p.ID,
-								Level: int32(p.Level),
-							})
-						}
-					}
-
-					err := stream.Send(resp)
-					if err != nil {
-						s.logger.DebugContext(ctx, "failed to send status", slog.String("player_id", player.ID), slog.String("error", err.Error()))
-					}
-				}
-			}
+			s.deliver(ctx, match)
+		}
+	}
+}
+
+// deliver sends the session to subscribed players
+func (s *MatchmakingServer) deliver(ctx context.Context, match matchmaking.MatchSession) {
+	_, span := tracer.Start(match.TraceContext(ctx), "matchmaking.status.deliver", trace.WithAttributes(
+		attribute.String("matchmaking.session_id", match.ID),
+		attribute.String("matchmaking.type", match.Type),
+		attribute.Int("matchmaking.players", len(match.Players))))
+	defer span.End()
+
+	queued, dropped := 0, 0
+	resp := toStatusResponse(match)
+	for _, player := range match.Players {
+		s.l.RLock()
+		sub, ok := s.playerStates[player.ID]
+		s.l.RUnlock()
+		if !ok {
+			continue
 		}
+		if sub.push(resp) {
+			queued++
+			continue
+		}
+		dropped++
+		s.metrics.StatusDropped(s.config.StatusOverflowPolicy)
+		s.logger.DebugContext(ctx, "status update dropped", slog.String("player_id", player.ID), slog.String("policy", s.config.StatusOverflowPolicy))
+	}
+	span.SetAttributes(
+		attribute.Int("matchmaking.queued", queued),
+		attribute.Int("matchmaking.dropped", dropped))
+
+	for _, consumer := range s.watchers.publish(s.service.QueueName(), match, time.Now()) {
+		s.metrics.WatchEventDropped(consumer)
+		s.logger.WarnContext(ctx, "oldest unacknowledged match event dropped", slog.String("consumer", consumer))
 	}
+}
+
+func playerIDs(players []*gen.PlayerData) []string {
+	ids := make([]string, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
 
-	return nil
+func toStatusResponse(match matchmaking.MatchSession) *gen.StatusResponse {
+	resp := &gen.StatusResponse{
+		Id:      match.ID,
+		Created: timestamppb.New(match.Created),
+		Type:    match.Type,
+	}
+	if match.Type == matchmaking.ChangesTypeMatchFound {
+		resp.Players = make([]*gen.PlayerData, 0, len(match.Players))
+		for _, p := range match.Players {
+			resp.Players = append(resp.Players, &gen.PlayerData{
+				Id:    p.ID,
+				Level: int32(p.Level),
+			})
+		}
+		resp.Endpoint = toEndpoint(match.Endpoint)
+	}
+
+	return resp
+}
+
+func toEndpoint(endpoint *matchmaking.Endpoint) *gen.Endpoint {
+	if endpoint == nil {
+		return nil
+	}
+
+	return &gen.Endpoint{
+		Host:  endpoint.Host,
+		Port:  int32(endpoint.Port),
+		Token: endpoint.Token,
+	}
 }
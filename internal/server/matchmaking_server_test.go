@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"log/slog"
+	gen "matchmaking/generated/grpc"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"matchmaking/pkg/auth"
+	"net"
+	"strings"
+	"testing"
+)
+
+// testCallerKey metadata of the test caller, roles follow the subject separated by commas
+const testCallerKey = "test-caller"
+
+// withTestCaller authenticates the call as the subject with the roles
+func withTestCaller(ctx context.Context, subject string, roles ...string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, testCallerKey, strings.Join(append([]string{subject}, roles...), ","))
+}
+
+// testCallerInterceptor sets claims of the test caller instead of verifying a token
+func testCallerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if caller := md.Get(testCallerKey); len(caller) > 0 {
+		values := strings.Split(caller[0], ",")
+		ctx = auth.WithClaims(ctx, &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: values[0]}, Roles: values[1:]})
+	}
+
+	return handler(ctx, req)
+}
+
+// newTestGrpcClient serves the server over an in-memory listener and returns a client of it
+func newTestGrpcClient(t *testing.T, server *MatchmakingServer) gen.MatchmakingClient {
+	return gen.NewMatchmakingClient(newTestGrpcConn(t, server))
+}
+
+// newTestGrpcConn serves the server over an in-memory listener and returns a connection to it
+func newTestGrpcConn(t *testing.T, server *MatchmakingServer) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(testCallerInterceptor))
+	server.Register()(grpcServer)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// newTestServer returns a server of a service which is not started, so added players stay in its queue
+func newTestServer(config MatchmakingServerConfig, matchmakingConfig matchmaking.MatchmakingConfig) *MatchmakingServer {
+	logger := slog.New(slog.DiscardHandler)
+	service := matchmaking.NewService(logger, matchmakingConfig, matchmaking.NewStorage(), metrics.Noop{})
+	return NewMatchmakingServer(logger, config, service, metrics.Noop{})
+}
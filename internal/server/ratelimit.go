@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"matchmaking/pkg/auth"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// idle limiters are forgotten after this period
+	limiterIdleTimeout = 10 * time.Minute
+	// retry hint when the queue is overloaded
+	shedRetryDelay = time.Second
+)
+
+// limiterEntry token bucket of one key
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// keyedLimiter token buckets per key, idle buckets are swept periodically
+type keyedLimiter struct {
+	limit     rate.Limit
+	burst     int
+	l         sync.Mutex
+	limiters  map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+func newKeyedLimiter(rps float64, burst int) *keyedLimiter {
+	if rps <= 0 {
+		return nil
+	}
+
+	return &keyedLimiter{
+		limit:    rate.Limit(rps),
+		burst:    max(burst, 1),
+		limiters: make(map[string]*limiterEntry),
+	}
+}
+
+// reserve takes a token for the key, returns the reservation when allowed or how long to wait otherwise
+func (k *keyedLimiter) reserve(key string, now time.Time) (*rate.Reservation, time.Duration) {
+	k.l.Lock()
+	defer k.l.Unlock()
+
+	if now.Sub(k.lastSweep) > limiterIdleTimeout {
+		for key, entry := range k.limiters {
+			if now.Sub(entry.lastSeen) > limiterIdleTimeout {
+				delete(k.limiters, key)
+			}
+		}
+		k.lastSweep = now
+	}
+
+	entry, ok := k.limiters[key]
+	if !ok {
+		entry = &limiterEntry{limiter: rate.NewLimiter(k.limit, k.burst)}
+		k.limiters[key] = entry
+	}
+	entry.lastSeen = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return nil, delay
+	}
+
+	return reservation, 0
+}
+
+// rateLimiter limits calls per authenticated caller and per peer address
+type rateLimiter struct {
+	callers *keyedLimiter
+	peers   *keyedLimiter
+}
+
+func newRateLimiter(config MatchmakingServerConfig) *rateLimiter {
+	return &rateLimiter{
+		callers: newKeyedLimiter(config.RateLimitCallerRps, config.RateLimitCallerBurst),
+		peers:   newKeyedLimiter(config.RateLimitPeerRps, config.RateLimitPeerBurst),
+	}
+}
+
+// limit checks the caller and peer limits, a call rejected by the peer limit gives the caller token back
+func (s *MatchmakingServer) limit(ctx context.Context) error {
+	now := time.Now()
+	var caller *rate.Reservation
+	if s.limiter.callers != nil {
+		if claims, ok := auth.ClaimsFromContext(ctx); ok && !claims.HasRole(s.config.BackendRole) {
+			reservation, delay := s.limiter.callers.reserve(claims.Subject, now)
+			if delay > 0 {
+				return resourceExhausted(ctx, delay, "rate limit exceeded for caller %q", claims.Subject)
+			}
+			caller = reservation
+		}
+	}
+	if s.limiter.peers != nil {
+		if address := peerAddress(ctx); address != "" {
+			if _, delay := s.limiter.peers.reserve(address, now); delay > 0 {
+				if caller != nil {
+					caller.CancelAt(now)
+				}
+				return resourceExhausted(ctx, delay, "rate limit exceeded for peer %q", address)
+			}
+		}
+	}
+
+	return nil
+}
+
+// shed rejects new players when the command queue is almost full,
+// the remaining capacity is left for removals
+func (s *MatchmakingServer) shed(ctx context.Context) error {
+	if s.config.ShedQueuePercent <= 0 {
+		return nil
+	}
+
+	capacity := s.service.QueueCapacity()
+	threshold := int(math.Ceil(float64(capacity) * float64(s.config.ShedQueuePercent) / 100))
+	if capacity > 0 && s.service.QueueLength() >= threshold {
+		return resourceExhausted(ctx, shedRetryDelay, "matchmaking queue is overloaded")
+	}
+
+	return nil
+}
+
+// resourceExhausted returns ResourceExhausted with retry info details and retry-after trailer
+func resourceExhausted(ctx context.Context, delay time.Duration, format string, args ...any) error {
+	retryAfter := max(int(math.Ceil(delay.Seconds())), 1)
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+
+	st := status.Newf(codes.ResourceExhausted, format, args...)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// peerAddress returns the client host, for calls proxied by the gateway from loopback
+// the last X-Forwarded-For entry added by the gateway is used
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if forwarded := md.Get("x-forwarded-for"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			return strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+
+	return host
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	gen "matchmaking/generated/grpc"
+	"matchmaking/internal/matchmaking"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiterPerKey(t *testing.T) {
+	// Arrange
+	limiter := newKeyedLimiter(1, 2)
+	now := time.Now()
+
+	// Act
+	_, first := limiter.reserve("player-1", now)
+	_, second := limiter.reserve("player-1", now)
+	_, third := limiter.reserve("player-1", now)
+	_, other := limiter.reserve("player-2", now)
+	_, refilled := limiter.reserve("player-1", now.Add(time.Second))
+
+	// Assert
+	assert.Zero(t, first)
+	assert.Zero(t, second)
+	assert.InDelta(t, time.Second, third, float64(10*time.Millisecond))
+	assert.Zero(t, other)
+	assert.Zero(t, refilled)
+}
+
+func TestKeyedLimiterDisabled(t *testing.T) {
+	// Assert
+	assert.Nil(t, newKeyedLimiter(0, 10))
+}
+
+func TestKeyedLimiterSweepsIdleKeys(t *testing.T) {
+	// Arrange
+	limiter := newKeyedLimiter(1, 1)
+	now := time.Now()
+	limiter.reserve("player-1", now)
+
+	// Act
+	limiter.reserve("player-2", now.Add(2*limiterIdleTimeout))
+
+	// Assert
+	assert.NotContains(t, limiter.limiters, "player-1")
+	assert.Contains(t, limiter.limiters, "player-2")
+}
+
+func TestPeerAddressBehindGateway(t *testing.T) {
+	// Arrange
+	loopback := peer.NewContext(t.Context(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}})
+	proxied := metadata.NewIncomingContext(loopback, metadata.Pairs("x-forwarded-for", "10.0.0.1, 192.168.1.7"))
+	remote := peer.NewContext(t.Context(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 168, 1, 8), Port: 4000}})
+
+	// Act & Assert
+	assert.Equal(t, "127.0.0.1", peerAddress(loopback))
+	assert.Equal(t, "192.168.1.7", peerAddress(proxied))
+	assert.Equal(t, "192.168.1.8", peerAddress(remote))
+}
+
+// retryDelay returns the retry info delay of the error and the retry-after trailer
+func retryDelay(t *testing.T, err error, trailer metadata.MD) (time.Duration, []string) {
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code(), st.Message())
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.RetryDelay.AsDuration(), trailer.Get("retry-after")
+		}
+	}
+	require.Fail(t, "no retry info in error details")
+	return 0, nil
+}
+
+func TestAddPlayerRateLimitedCaller(t *testing.T) {
+	// Arrange
+	server := newTestServer(MatchmakingServerConfig{RateLimitCallerRps: 0.5, RateLimitCallerBurst: 1},
+		matchmaking.MatchmakingConfig{QueueSize: 10})
+	client := newTestGrpcClient(t, server)
+	ctx := withTestCaller(t.Context(), "player-1")
+	req := &gen.AddPlayerRequest{Players: []*gen.PlayerData{{Id: "player-1", Level: 1}}}
+	_, err := client.AddPlayer(ctx, req)
+	require.NoError(t, err)
+
+	// Act
+	var trailer metadata.MD
+	_, errLimited := client.AddPlayer(ctx, req, grpc.Trailer(&trailer))
+	_, errBackend := client.AddPlayer(withTestCaller(t.Context(), "backend-1", "backend"), req)
+	_, errOther := client.AddPlayer(withTestCaller(t.Context(), "player-2"), req)
+
+	// Assert
+	delay, retryAfter := retryDelay(t, errLimited, trailer)
+	assert.InDelta(t, 2*time.Second, delay, float64(100*time.Millisecond))
+	assert.Equal(t, []string{"2"}, retryAfter)
+	assert.NoError(t, errBackend)
+	assert.NoError(t, errOther)
+}
+
+func TestRateLimitedPeerKeepsCallerToken(t *testing.T) {
+	// Arrange
+	server := newTestServer(MatchmakingServerConfig{
+		RateLimitCallerRps:   0.001,
+		RateLimitCallerBurst: 1,
+		RateLimitPeerRps:     0.001,
+		RateLimitPeerBurst:   1,
+	}, matchmaking.MatchmakingConfig{QueueSize: 10})
+	client := newTestGrpcClient(t, server)
+	req := func(id string) *gen.RemovePlayerRequest {
+		return &gen.RemovePlayerRequest{Players: []*gen.PlayerData{{Id: id}}}
+	}
+	_, err := client.RemovePlayer(withTestCaller(t.Context(), "player-1"), req("player-1"))
+	require.NoError(t, err)
+
+	// Act
+	_, errPeer := client.RemovePlayer(withTestCaller(t.Context(), "player-2"), req("player-2"))
+	_, errCaller := client.RemovePlayer(withTestCaller(t.Context(), "player-2"), req("player-2"))
+
+	// Assert
+	assert.Contains(t, status.Convert(errPeer).Message(), "peer")
+	assert.Contains(t, status.Convert(errCaller).Message(), "peer")
+	_, callerDelay := server.limiter.callers.reserve("player-2", time.Now())
+	assert.Zero(t, callerDelay)
+}
+
+func TestAddPlayerShedsWhenQueueIsAlmostFull(t *testing.T) {
+	// Arrange
+	server := newTestServer(MatchmakingServerConfig{ShedQueuePercent: 50}, matchmaking.MatchmakingConfig{QueueSize: 2})
+	client := newTestGrpcClient(t, server)
+	_, err := client.AddPlayer(t.Context(), &gen.AddPlayerRequest{Players: []*gen.PlayerData{{Id: "player-1", Level: 1}}})
+	require.NoError(t, err)
+
+	// Act
+	var trailer metadata.MD
+	_, errShed := client.AddPlayer(t.Context(), &gen.AddPlayerRequest{Players: []*gen.PlayerData{{Id: "player-2", Level: 1}}}, grpc.Trailer(&trailer))
+	_, errRemove := client.RemovePlayer(t.Context(), &gen.RemovePlayerRequest{Players: []*gen.PlayerData{{Id: "player-1"}}})
+
+	// Assert
+	delay, retryAfter := retryDelay(t, errShed, trailer)
+	assert.Equal(t, shedRetryDelay, delay)
+	assert.Equal(t, []string{"1"}, retryAfter)
+	assert.NoError(t, errRemove)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	gen "matchmaking/generated/grpc"
+	"sync"
+)
+
+type OverflowPolicy = string
+
+const (
+	// OverflowDropOldest drops the oldest queued update to make room for the new one
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDisconnect ends the status stream of a player who does not keep up
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+var (
+	errStatusOverflow = errors.New("status updates are not consumed fast enough")
+	errServerClosing  = errors.New("server is shutting down")
+)
+
+// subscriber delivers status updates to one player through a bounded queue,
+// so a slow player does not delay updates of other players
+type subscriber struct {
+	playerID string
+	sender   statusSender
+	policy   OverflowPolicy
+	queue    chan *gen.StatusResponse
+	// closing is closed by the server on shutdown, queued updates are flushed before the subscriber ends
+	closing <-chan struct{}
+	// done is closed when the subscriber ends, exited when its goroutine returns
+	done     chan struct{}
+	exited   chan struct{}
+	err      error
+	doneOnce sync.Once
+}
+
+func newSubscriber(playerID string, sender statusSender, size int, policy OverflowPolicy, closing <-chan struct{}) *subscriber {
+	return &subscriber{
+		playerID: playerID,
+		sender:   sender,
+		policy:   policy,
+		queue:    make(chan *gen.StatusResponse, max(size, 1)),
+		closing:  closing,
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+}
+
+// push queues the update without blocking, returns false when an update was dropped
+func (s *subscriber) push(resp *gen.StatusResponse) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+
+	dropped := false
+	for {
+		select {
+		case s.queue <- resp:
+			return !dropped
+		default:
+		}
+
+		if s.policy == OverflowDisconnect {
+			s.end(errStatusOverflow)
+			return false
+		}
+
+		// the oldest update may be taken by the sender meanwhile, then the next attempt succeeds
+		select {
+		case <-s.queue:
+			dropped = true
+		default:
+		}
+	}
+}
+
+// run sends queued updates until the subscriber ends
+func (s *subscriber) run(logger *slog.Logger) {
+	defer close(s.exited)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case resp := <-s.queue:
+			if !s.send(logger, resp) {
+				return
+			}
+		case <-s.closing:
+			for {
+				select {
+				case resp := <-s.queue:
+					if !s.send(logger, resp) {
+						return
+					}
+				default:
+					s.end(errServerClosing)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *subscriber) send(logger *slog.Logger, resp *gen.StatusResponse) bool {
+	if err := s.sender.Send(resp); err != nil {
+		logger.Debug("failed to send status", slog.String("player_id", s.playerID), slog.String("error", err.Error()))
+		s.end(err)
+		return false
+	}
+
+	return true
+}
+
+// end stops the subscriber with the reason, the first reason wins
+func (s *subscriber) end(err error) {
+	s.doneOnce.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// close ends the subscriber and waits for its goroutine, so the sender is not used afterwards
+func (s *subscriber) close() {
+	s.end(nil)
+	<-s.exited
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	gen "matchmaking/generated/grpc"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingSender records sent updates, every send waits until release is closed
+type blockingSender struct {
+	release chan struct{}
+	l       sync.Mutex
+	sent    []string
+}
+
+func (b *blockingSender) Send(resp *gen.StatusResponse) error {
+	<-b.release
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.sent = append(b.sent, resp.Id)
+	return nil
+}
+
+func (b *blockingSender) ids() []string {
+	b.l.Lock()
+	defer b.l.Unlock()
+	return append([]string(nil), b.sent...)
+}
+
+func TestSubscriberDropOldest(t *testing.T) {
+	// Arrange
+	sender := &blockingSender{release: make(chan struct{})}
+	sub := newSubscriber("player-1", sender, 2, OverflowDropOldest, make(chan struct{}))
+
+	// Act
+	first := sub.push(&gen.StatusResponse{Id: "1"})
+	second := sub.push(&gen.StatusResponse{Id: "2"})
+	third := sub.push(&gen.StatusResponse{Id: "3"})
+	go sub.run(slog.Default())
+	close(sender.release)
+
+	// Assert
+	assert.True(t, first)
+	assert.True(t, second)
+	assert.False(t, third)
+	assert.Eventually(t, func() bool { return len(sender.ids()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"2", "3"}, sender.ids())
+	sub.close()
+}
+
+func TestSubscriberDisconnect(t *testing.T) {
+	// Arrange
+	sender := &blockingSender{release: make(chan struct{})}
+	sub := newSubscriber("player-1", sender, 1, OverflowDisconnect, make(chan struct{}))
+
+	// Act
+	first := sub.push(&gen.StatusResponse{Id: "1"})
+	second := sub.push(&gen.StatusResponse{Id: "2"})
+
+	// Assert
+	assert.True(t, first)
+	assert.False(t, second)
+	<-sub.done
+	assert.ErrorIs(t, sub.err, errStatusOverflow)
+	assert.False(t, sub.push(&gen.StatusResponse{Id: "3"}))
+}
+
+func TestSubscriberFlushesOnClosing(t *testing.T) {
+	// Arrange
+	sender := &blockingSender{release: make(chan struct{})}
+	close(sender.release)
+	closing := make(chan struct{})
+	sub := newSubscriber("player-1", sender, 4, OverflowDropOldest, closing)
+	require.True(t, sub.push(&gen.StatusResponse{Id: "1"}))
+	require.True(t, sub.push(&gen.StatusResponse{Id: "2"}))
+
+	// Act
+	close(closing)
+	sub.run(slog.Default())
+
+	// Assert
+	assert.True(t, errors.Is(sub.err, errServerClosing))
+	assert.Equal(t, []string{"1", "2"}, sender.ids())
+}
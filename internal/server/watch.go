@@ -0,0 +1,207 @@
+package server
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	gen "matchmaking/generated/grpc"
+	"matchmaking/internal/matchmaking"
+	"slices"
+	"sync"
+	"time"
+)
+
+var errConsumerNotFound = errors.New("consumer is not watching matches")
+
+// minWatchAckTimeout keeps streams from redelivering in a busy loop
+const minWatchAckTimeout = time.Second
+
+// watchHub keeps match events of backend consumers until they are acknowledged,
+// events not acknowledged within the ack timeout are sent again
+type watchHub struct {
+	l           sync.Mutex
+	consumers   map[string]*watchConsumer
+	pendingSize int
+	ackTimeout  time.Duration
+	consumerTTL time.Duration
+}
+
+// watchConsumer is a named backend consumer, it outlives its streams so events are redelivered after reconnect
+type watchConsumer struct {
+	name    string
+	queue   string
+	types   []string
+	pending []*pendingEvent
+	// dropped counts pending events dropped since the last sent event
+	dropped uint32
+	// stream currently watching, nil when the consumer is disconnected
+	stream *watchStream
+	// lastSeen is when the last stream of the consumer ended
+	lastSeen time.Time
+}
+
+// watchStream is one stream of a consumer, replaced is closed when another stream takes the consumer over
+type watchStream struct {
+	consumer *watchConsumer
+	notify   chan struct{}
+	replaced chan struct{}
+}
+
+type pendingEvent struct {
+	event  *gen.MatchEvent
+	sentAt time.Time
+}
+
+func newWatchHub(config MatchmakingServerConfig) *watchHub {
+	return &watchHub{
+		consumers:   make(map[string]*watchConsumer),
+		pendingSize: max(config.WatchPendingSize, 1),
+		ackTimeout:  max(config.WatchAckTimeout(), minWatchAckTimeout),
+		consumerTTL: config.WatchConsumerTTL(),
+	}
+}
+
+// watch starts a stream of the consumer, a previous stream of the consumer is replaced
+// and all pending events are sent again
+func (h *watchHub) watch(name, queue string, types []string) *watchStream {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	consumer, ok := h.consumers[name]
+	if !ok {
+		consumer = &watchConsumer{name: name}
+		h.consumers[name] = consumer
+	}
+	consumer.queue = queue
+	consumer.types = types
+	if consumer.stream != nil {
+		close(consumer.stream.replaced)
+	}
+	for _, p := range consumer.pending {
+		p.sentAt = time.Time{}
+	}
+
+	consumer.stream = &watchStream{
+		consumer: consumer,
+		notify:   make(chan struct{}, 1),
+		replaced: make(chan struct{}),
+	}
+	return consumer.stream
+}
+
+// release ends the stream, the consumer keeps its pending events until the consumer TTL expires
+func (h *watchHub) release(stream *watchStream) {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	if stream.consumer.stream == stream {
+		stream.consumer.stream = nil
+		stream.consumer.lastSeen = time.Now()
+	}
+}
+
+// publish keeps the event for every consumer watching its queue and type, the oldest pending event is dropped
+// when the consumer has too many, returns names of consumers which lost their oldest pending event
+func (h *watchHub) publish(queue string, match matchmaking.MatchSession, now time.Time) []string {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	var overflowed []string
+	for name, consumer := range h.consumers {
+		if consumer.stream == nil && now.Sub(consumer.lastSeen) > h.consumerTTL {
+			delete(h.consumers, name)
+			continue
+		}
+		if !consumer.watches(queue, match.Type) {
+			continue
+		}
+
+		consumer.pending = append(consumer.pending, &pendingEvent{event: toMatchEvent(queue, match)})
+		if len(consumer.pending) > h.pendingSize {
+			consumer.dropped += uint32(len(consumer.pending) - h.pendingSize)
+			consumer.pending = slices.Delete(consumer.pending, 0, len(consumer.pending)-h.pendingSize)
+			overflowed = append(overflowed, name)
+		}
+		if consumer.stream != nil {
+			select {
+			case consumer.stream.notify <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	return overflowed
+}
+
+// due returns events the stream should send now and the time to wait before the next redelivery,
+// the first event counts the events dropped since the last sent one, a replaced stream gets no events
+func (h *watchHub) due(stream *watchStream, now time.Time) ([]*gen.MatchEvent, time.Duration) {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	wait := h.ackTimeout
+	if stream.consumer.stream != stream {
+		return nil, wait
+	}
+
+	var events []*gen.MatchEvent
+	for _, p := range stream.consumer.pending {
+		if !p.sentAt.IsZero() && now.Sub(p.sentAt) < h.ackTimeout {
+			wait = min(wait, h.ackTimeout-now.Sub(p.sentAt))
+			continue
+		}
+
+		p.sentAt = now
+		p.event.Attempt++
+		events = append(events, proto.Clone(p.event).(*gen.MatchEvent))
+	}
+	if len(events) > 0 {
+		events[0].Dropped = stream.consumer.dropped
+		stream.consumer.dropped = 0
+	}
+
+	return events, wait
+}
+
+// ack removes acknowledged events of the consumer, unknown event IDs are ignored
+func (h *watchHub) ack(name string, ids []string) error {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	consumer, ok := h.consumers[name]
+	if !ok {
+		return errConsumerNotFound
+	}
+	consumer.pending = slices.DeleteFunc(consumer.pending, func(p *pendingEvent) bool {
+		return slices.Contains(ids, p.event.Id)
+	})
+
+	return nil
+}
+
+func (c *watchConsumer) watches(queue string, changeType matchmaking.PlayerChangesType) bool {
+	if c.queue != "" && c.queue != queue {
+		return false
+	}
+
+	return len(c.types) == 0 || slices.Contains(c.types, changeType)
+}
+
+func toMatchEvent(queue string, match matchmaking.MatchSession) *gen.MatchEvent {
+	event := &gen.MatchEvent{
+		Id:       match.ID,
+		Queue:    queue,
+		Created:  timestamppb.New(match.Created),
+		Type:     match.Type,
+		Players:  make([]*gen.PlayerData, 0, len(match.Players)),
+		Endpoint: toEndpoint(match.Endpoint),
+	}
+	for _, p := range match.Players {
+		event.Players = append(event.Players, &gen.PlayerData{
+			Id:    p.ID,
+			Level: int32(p.Level),
+		})
+	}
+
+	return event
+}
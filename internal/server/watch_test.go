@@ -0,0 +1,148 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"matchmaking/internal/matchmaking"
+	"testing"
+	"time"
+)
+
+func newTestWatchHub() *watchHub {
+	return newWatchHub(MatchmakingServerConfig{
+		WatchAckTimeoutSeconds:  10,
+		WatchPendingSize:        2,
+		WatchConsumerTTLSeconds: 60,
+	})
+}
+
+func TestWatchRedeliversUnacknowledged(t *testing.T) {
+	// Arrange
+	hub := newTestWatchHub()
+	stream := hub.watch("orchestrator", "", nil)
+	now := time.Now()
+	match := matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "1", Level: 1})
+	hub.publish("default", match, now)
+
+	// Act
+	first, wait := hub.due(stream, now)
+	early, _ := hub.due(stream, now.Add(time.Second))
+	retried, _ := hub.due(stream, now.Add(10*time.Second))
+	require.NoError(t, hub.ack("orchestrator", []string{match.ID}))
+	acked, _ := hub.due(stream, now.Add(30*time.Second))
+
+	// Assert
+	require.Len(t, first, 1)
+	assert.Equal(t, match.ID, first[0].Id)
+	assert.Equal(t, "default", first[0].Queue)
+	assert.EqualValues(t, 1, first[0].Attempt)
+	assert.Equal(t, 10*time.Second, wait)
+	assert.Empty(t, early)
+	require.Len(t, retried, 1)
+	assert.EqualValues(t, 2, retried[0].Attempt)
+	assert.Empty(t, acked)
+}
+
+func TestWatchFiltersQueueAndType(t *testing.T) {
+	// Arrange
+	hub := newTestWatchHub()
+	matched := hub.watch("matched", "", []string{matchmaking.ChangesTypeMatchFound})
+	other := hub.watch("other", "ranked", nil)
+	now := time.Now()
+
+	// Act
+	hub.publish("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound), now)
+	hub.publish("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeTimeout), now)
+	matchedEvents, _ := hub.due(matched, now)
+	otherEvents, _ := hub.due(other, now)
+
+	// Assert
+	require.Len(t, matchedEvents, 1)
+	assert.Equal(t, matchmaking.ChangesTypeMatchFound, matchedEvents[0].Type)
+	assert.Empty(t, otherEvents)
+}
+
+func TestWatchReconnectReplacesStream(t *testing.T) {
+	// Arrange
+	hub := newTestWatchHub()
+	first := hub.watch("orchestrator", "", nil)
+	now := time.Now()
+	hub.publish("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound), now)
+	sent, _ := hub.due(first, now)
+	require.Len(t, sent, 1)
+
+	// Act
+	second := hub.watch("orchestrator", "", nil)
+	hub.release(first)
+	replacedEvents, _ := hub.due(first, now)
+	redelivered, _ := hub.due(second, now)
+
+	// Assert
+	assert.Empty(t, replacedEvents)
+	require.Len(t, redelivered, 1)
+	assert.EqualValues(t, 2, redelivered[0].Attempt)
+	select {
+	case <-first.replaced:
+	default:
+		assert.Fail(t, "first stream is not replaced")
+	}
+}
+
+func TestWatchKeepsNewestPending(t *testing.T) {
+	// Arrange
+	hub := newTestWatchHub()
+	stream := hub.watch("orchestrator", "", nil)
+	now := time.Now()
+	sessions := []matchmaking.MatchSession{
+		matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound),
+		matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound),
+		matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound),
+	}
+
+	// Act
+	var overflowed []string
+	for _, session := range sessions {
+		overflowed = append(overflowed, hub.publish("default", session, now)...)
+	}
+	events, _ := hub.due(stream, now)
+	retried, _ := hub.due(stream, now.Add(10*time.Second))
+
+	// Assert
+	assert.Equal(t, []string{"orchestrator"}, overflowed)
+	require.Len(t, events, 2)
+	assert.Equal(t, sessions[1].ID, events[0].Id)
+	assert.Equal(t, sessions[2].ID, events[1].Id)
+	assert.EqualValues(t, 1, events[0].Dropped)
+	assert.Zero(t, events[1].Dropped)
+	require.Len(t, retried, 2)
+	assert.Zero(t, retried[0].Dropped)
+}
+
+func TestWatchAckTimeoutIsPositive(t *testing.T) {
+	// Arrange
+	hub := newWatchHub(MatchmakingServerConfig{WatchPendingSize: 2})
+	stream := hub.watch("orchestrator", "", nil)
+	now := time.Now()
+	hub.publish("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound), now)
+
+	// Act
+	first, wait := hub.due(stream, now)
+	again, _ := hub.due(stream, now)
+
+	// Assert
+	assert.Len(t, first, 1)
+	assert.Equal(t, minWatchAckTimeout, wait)
+	assert.Empty(t, again)
+}
+
+func TestWatchExpiresDisconnectedConsumer(t *testing.T) {
+	// Arrange
+	hub := newTestWatchHub()
+	hub.release(hub.watch("orchestrator", "", nil))
+
+	// Act
+	hub.publish("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound), time.Now().Add(time.Hour))
+
+	// Assert
+	assert.ErrorIs(t, hub.ack("orchestrator", nil), errConsumerNotFound)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"log/slog"
+	gen "matchmaking/generated/grpc"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// time allowed to write a message to the peer
+	webSocketWriteWait = 10 * time.Second
+	// time allowed to read the next pong message from the peer
+	webSocketPongWait = 60 * time.Second
+	// send pings to peer with this period, must be less than webSocketPongWait
+	webSocketPingPeriod = webSocketPongWait * 9 / 10
+	// maximum message size allowed from peer, clients are not expected to send anything but control frames
+	webSocketMaxMessageSize = 512
+)
+
+var errPlayerNotProvided = errors.New("player id is not provided")
+
+// PlayerAuthenticator resolves the player identity of an HTTP request
+type PlayerAuthenticator func(request *http.Request) (string, error)
+
+// SetPlayerAuthenticator replaces the authenticator used by the WebSocket status channel
+func (s *MatchmakingServer) SetPlayerAuthenticator(authenticator PlayerAuthenticator) *MatchmakingServer {
+	s.authenticate = authenticator
+	return s
+}
+
+// StatusWebSocket streams status updates of the authenticated player over WebSocket as JSON frames
+func (s *MatchmakingServer) StatusWebSocket(writer http.ResponseWriter, request *http.Request, _ map[string]string) {
+	playerID, err := s.authenticate(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(writer, request, nil)
+	if err != nil {
+		s.logger.DebugContext(request.Context(), "failed to upgrade websocket", slog.String("player_id", playerID), slog.String("error", err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	sub := s.subscribe(playerID, &webSocketSender{conn: conn})
+	defer s.unsubscribe(sub)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(webSocketMaxMessageSize)
+		_ = conn.SetReadDeadline(time.Now().Add(webSocketPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(webSocketPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					s.logger.DebugContext(request.Context(), "websocket closed", slog.String("player_id", playerID), slog.String("error", err.Error()))
+				}
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(webSocketPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-sub.done:
+			code := websocket.CloseGoingAway
+			if errors.Is(sub.err, errStatusOverflow) {
+				code = websocket.ClosePolicyViolation
+			}
+			if sub.err != nil {
+				closeMessage := websocket.FormatCloseMessage(code, sub.err.Error())
+				_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(webSocketWriteWait))
+			}
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// webSocketSender sends status updates to a WebSocket connection
+type webSocketSender struct {
+	conn *websocket.Conn
+	l    sync.Mutex
+}
+
+func (w *webSocketSender) Send(resp *gen.StatusResponse) error {
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(webSocketWriteWait)); err != nil {
+		return err
+	}
+
+	return w.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// queryPlayerAuthenticator takes the player identity from the player_id query parameter
+func queryPlayerAuthenticator(request *http.Request) (string, error) {
+	playerID := request.URL.Query().Get("player_id")
+	if playerID == "" {
+		return "", errPlayerNotProvided
+	}
+
+	return playerID, nil
+}
+
+func newUpgrader(config MatchmakingServerConfig) websocket.Upgrader {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	if len(config.WebSocketAllowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(request *http.Request) bool {
+			origin := request.Header.Get("Origin")
+			return origin == "" || slices.Contains(config.WebSocketAllowedOrigins, "*") || slices.Contains(config.WebSocketAllowedOrigins, origin)
+		}
+	}
+
+	return upgrader
+}
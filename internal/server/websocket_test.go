@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestWebSocketServer(t *testing.T, config MatchmakingServerConfig) (*MatchmakingServer, string) {
+	config.StatusQueueSize = 4
+	config.StatusOverflowPolicy = OverflowDropOldest
+	logger := slog.New(slog.DiscardHandler)
+	service := matchmaking.NewService(logger, matchmaking.MatchmakingConfig{QueueSize: 10}, matchmaking.NewStorage(), metrics.Noop{})
+	server := NewMatchmakingServer(logger, config, service, metrics.Noop{})
+	httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		server.StatusWebSocket(writer, request, nil)
+	}))
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(server.Close)
+
+	return server, "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+func (s *MatchmakingServer) subscribed(playerID string) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	_, ok := s.playerStates[playerID]
+	return ok
+}
+
+func TestStatusWebSocketSendsJSONFrames(t *testing.T) {
+	// Arrange
+	server, url := newTestWebSocketServer(t, MatchmakingServerConfig{})
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"?player_id=player-1", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return server.subscribed("player-1") }, time.Second, time.Millisecond)
+	match := matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "player-1", Level: 5})
+
+	// Act
+	server.deliver(t.Context(), match)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	messageType, data, err := conn.ReadMessage()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, websocket.TextMessage, messageType)
+	var frame map[string]any
+	require.NoError(t, json.Unmarshal(data, &frame))
+	assert.Equal(t, match.ID, frame["id"])
+	assert.Equal(t, matchmaking.ChangesTypeMatchFound, frame["type"])
+	created, err := time.Parse(time.RFC3339Nano, frame["created"].(string))
+	require.NoError(t, err)
+	assert.True(t, match.Created.Equal(created), "created %s, expected %s", created, match.Created)
+	assert.Equal(t, []any{map[string]any{"id": "player-1", "level": 5.0}}, frame["players"])
+}
+
+func TestStatusWebSocketRequiresPlayer(t *testing.T) {
+	// Arrange
+	_, url := newTestWebSocketServer(t, MatchmakingServerConfig{})
+
+	// Act
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestStatusWebSocketRejectsOrigin(t *testing.T) {
+	// Arrange
+	server, url := newTestWebSocketServer(t, MatchmakingServerConfig{WebSocketAllowedOrigins: []string{"https://game.example"}})
+
+	// Act
+	_, rejected, errRejected := websocket.DefaultDialer.Dial(url+"?player_id=player-1", http.Header{"Origin": {"https://other.example"}})
+	allowed, _, errAllowed := websocket.DefaultDialer.Dial(url+"?player_id=player-2", http.Header{"Origin": {"https://game.example"}})
+
+	// Assert
+	require.Error(t, errRejected)
+	assert.Equal(t, http.StatusForbidden, rejected.StatusCode)
+	assert.False(t, server.subscribed("player-1"))
+	require.NoError(t, errAllowed)
+	_ = allowed.Close()
+}
+
+func TestStatusWebSocketUnsubscribesOnClose(t *testing.T) {
+	// Arrange
+	server, url := newTestWebSocketServer(t, MatchmakingServerConfig{})
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?player_id=player-1", nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return server.subscribed("player-1") }, time.Second, time.Millisecond)
+
+	// Act
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	require.NoError(t, conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second)))
+	_ = conn.Close()
+
+	// Assert
+	assert.Eventually(t, func() bool { return !server.subscribed("player-1") }, time.Second, time.Millisecond)
+}
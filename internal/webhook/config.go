@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type WebhookConfig struct {
+	WebhookTargetsFile    string `env:"WEBHOOK_TARGETS_FILE"`
+	WebhookSecret         string `env:"WEBHOOK_SECRET"`
+	WebhookMaxAttempts    int    `env:"WEBHOOK_MAX_ATTEMPTS, default=5"`
+	WebhookBackoffMillis  int    `env:"WEBHOOK_BACKOFF_MILLISECONDS, default=500"`
+	WebhookTimeoutSeconds int    `env:"WEBHOOK_TIMEOUT_SECONDS, default=5"`
+	WebhookQueueSize      int    `env:"WEBHOOK_QUEUE_SIZE, default=100"`
+	WebhookDeadLetterFile string `env:"WEBHOOK_DEAD_LETTER_FILE, default=webhooks.dead.jsonl"`
+}
+
+// Target is an HTTP endpoint receiving events
+type Target struct {
+	URL string `json:"url"`
+	// Secret signs payloads, WEBHOOK_SECRET is used when it is empty
+	Secret string `json:"secret"`
+	// Types of events sent to the target, all types when empty
+	Types []string `json:"types"`
+}
+
+// Enabled webhooks are sent when the targets file is configured
+func (c WebhookConfig) Enabled() bool {
+	return c.WebhookTargetsFile != ""
+}
+
+// Backoff delay before the first retry, doubled on every next retry
+func (c WebhookConfig) Backoff() time.Duration {
+	return time.Duration(c.WebhookBackoffMillis) * time.Millisecond
+}
+
+func (c WebhookConfig) Timeout() time.Duration {
+	return time.Duration(c.WebhookTimeoutSeconds) * time.Second
+}
+
+// Targets reads targets from the JSON targets file
+func (c WebhookConfig) Targets() ([]Target, error) {
+	data, err := os.ReadFile(c.WebhookTargetsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("invalid webhook targets file %s: %w", c.WebhookTargetsFile, err)
+	}
+	for i, target := range targets {
+		if target.URL == "" {
+			return nil, fmt.Errorf("webhook target %d has no url", i)
+		}
+		if target.Secret == "" {
+			targets[i].Secret = c.WebhookSecret
+		}
+		if targets[i].Secret == "" {
+			return nil, fmt.Errorf("webhook target %s has no secret", target.URL)
+		}
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// deadLetter appends events which were not delivered to a JSON lines file
+type deadLetter struct {
+	l    sync.Mutex
+	path string
+	file *os.File
+}
+
+type deadLetterRecord struct {
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Failed   time.Time `json:"failed"`
+}
+
+func newDeadLetter(path string) *deadLetter {
+	return &deadLetter{path: path}
+}
+
+// write appends the event, the file is created on the first write
+func (d *deadLetter) write(url string, event Event, attempts int, cause error) error {
+	line, err := json.Marshal(deadLetterRecord{
+		URL:      url,
+		Event:    event,
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Failed:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+	if d.file == nil {
+		d.file, err = os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = d.file.Write(append(line, '\n'))
+
+	return err
+}
+
+func (d *deadLetter) close() error {
+	d.l.Lock()
+	defer d.l.Unlock()
+	if d.file == nil {
+		return nil
+	}
+
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"matchmaking/internal/events"
+	"matchmaking/internal/metrics"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxBackoff limits the delay between retries
+	maxBackoff = 30 * time.Second
+
+	SignatureHeader = "X-Matchmaking-Signature"
+	EventIDHeader   = "X-Matchmaking-Event-Id"
+	EventTypeHeader = "X-Matchmaking-Event-Type"
+	AttemptHeader   = "X-Matchmaking-Attempt"
+)
+
+type DeliveryResult = string
+
+const (
+	DeliveryDelivered    DeliveryResult = "delivered"
+	DeliveryRetried      DeliveryResult = "retried"
+	DeliveryDeadLettered DeliveryResult = "dead_lettered"
+)
+
+// PublisherName labels logs and metrics of webhook targets in the events fanout
+const PublisherName = "webhook"
+
+// Event is the JSON payload of a webhook
+type Event = events.Event
+
+// Sink posts events to webhook targets, every target is a publisher of the events fanout,
+// so a slow target does not delay the others
+type Sink struct {
+	logger     *slog.Logger
+	config     WebhookConfig
+	metrics    metrics.Recorder
+	client     *http.Client
+	targets    []Target
+	deadLetter *deadLetter
+}
+
+// targetPublisher posts events of the watched types to one target
+type targetPublisher struct {
+	sink   *Sink
+	target Target
+}
+
+var (
+	_ events.EventPublisher = (*targetPublisher)(nil)
+	_ events.DropHandler    = (*targetPublisher)(nil)
+)
+
+// retryableError is a failed attempt which may succeed later
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e retryableError) Unwrap() error {
+	return e.err
+}
+
+// NewSink creates a sink of the targets file
+func NewSink(logger *slog.Logger, config WebhookConfig, recorder metrics.Recorder) (*Sink, error) {
+	targets, err := config.Targets()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sink{
+		logger:     logger,
+		config:     config,
+		metrics:    recorder,
+		client:     &http.Client{Timeout: config.Timeout()},
+		targets:    targets,
+		deadLetter: newDeadLetter(config.WebhookDeadLetterFile),
+	}, nil
+}
+
+// AddTo adds a publisher of every target to the fanout, each one keeps up to WEBHOOK_QUEUE_SIZE events waiting.
+// After ctx of the fanout is done the remaining events are written to the dead-letter file without retries.
+func (s *Sink) AddTo(fanout *events.Fanout) *events.Fanout {
+	for _, t := range s.targets {
+		fanout.AddSized(PublisherName, &targetPublisher{sink: s, target: t}, s.config.WebhookQueueSize)
+	}
+
+	return fanout
+}
+
+// Publish delivers the event unless the target does not watch its type
+func (p *targetPublisher) Publish(ctx context.Context, event Event) error {
+	if len(p.target.Types) > 0 && !slices.Contains(p.target.Types, event.Type) {
+		return nil
+	}
+
+	return p.sink.deliver(ctx, p.target, event)
+}
+
+// Dropped writes the event which did not fit into the queue of the target to the dead-letter file
+func (p *targetPublisher) Dropped(ctx context.Context, event Event, err error) {
+	if len(p.target.Types) > 0 && !slices.Contains(p.target.Types, event.Type) {
+		return
+	}
+
+	_ = p.sink.drop(ctx, p.target, event, 0, err)
+}
+
+// Close closes the dead-letter file shared by targets, it is opened again by a later write
+func (p *targetPublisher) Close() error {
+	return p.sink.deadLetter.close()
+}
+
+// deliver posts the event, retrying with exponential backoff, and writes it to the dead-letter file
+// when attempts are exhausted or the target rejects it
+func (s *Sink) deliver(ctx context.Context, t Target, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return s.drop(ctx, t, event, 0, err)
+	}
+
+	attempts := 0
+	for attempts < max(s.config.WebhookMaxAttempts, 1) {
+		if attempts > 0 {
+			s.metrics.WebhookDelivery(DeliveryRetried)
+			select {
+			case <-ctx.Done():
+				return s.drop(ctx, t, event, attempts, err)
+			case <-time.After(retryBackoff(s.config.Backoff(), attempts-1)):
+			}
+		}
+		if ctx.Err() != nil {
+			return s.drop(ctx, t, event, attempts, ctx.Err())
+		}
+
+		attempts++
+		err = s.post(ctx, t, event, body, attempts)
+		if err == nil {
+			s.metrics.WebhookDelivery(DeliveryDelivered)
+			return nil
+		}
+		s.logger.DebugContext(ctx, "webhook attempt failed", slog.String("url", t.URL), slog.String("event_id", event.ID), slog.Int("attempt", attempts), slog.String("error", err.Error()))
+		if !errors.As(err, &retryableError{}) {
+			break
+		}
+	}
+
+	return s.drop(ctx, t, event, attempts, err)
+}
+
+// retryBackoff doubles the delay on every retry up to maxBackoff
+func retryBackoff(base time.Duration, retries int) time.Duration {
+	backoff := base
+	for range retries {
+		if backoff >= maxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+
+	return min(backoff, maxBackoff)
+}
+
+func (s *Sink) post(ctx context.Context, t Target, event Event, body []byte, attempt int) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventIDHeader, event.ID)
+	request.Header.Set(EventTypeHeader, event.Type)
+	request.Header.Set(AttemptHeader, strconv.Itoa(attempt))
+	request.Header.Set(SignatureHeader, Sign(t.Secret, time.Now().Unix(), body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return retryableError{err: err}
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode == http.StatusRequestTimeout,
+		response.StatusCode == http.StatusTooManyRequests,
+		response.StatusCode >= 500:
+		return retryableError{err: fmt.Errorf("target responded %s", response.Status)}
+	default:
+		return fmt.Errorf("target rejected event: %s", response.Status)
+	}
+}
+
+// drop writes the event to the dead-letter file and returns the cause for the fanout to log
+func (s *Sink) drop(ctx context.Context, t Target, event Event, attempts int, cause error) error {
+	s.metrics.WebhookDelivery(DeliveryDeadLettered)
+	if err := s.deadLetter.write(t.URL, event, attempts, cause); err != nil {
+		s.logger.ErrorContext(ctx, "failed to write webhook dead letter", slog.String("file", s.config.WebhookDeadLetterFile), slog.String("error", err.Error()))
+	}
+
+	return fmt.Errorf("webhook to %s dead-lettered after %d attempts: %w", t.URL, attempts, cause)
+}
+
+// Sign returns the signature header value of the body sent at the unix timestamp,
+// "t=<timestamp>,v1=<hex HMAC-SHA256 of timestamp.body>", receivers compute it again to verify the payload
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%d.", timestamp)
+	_, _ = mac.Write(body)
+
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"matchmaking/internal/events"
+	"matchmaking/internal/matchmaking"
+	"matchmaking/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var emptyLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError}))
+
+// newTestSink writes the targets file and creates a sink retrying without delay
+func newTestSink(t *testing.T, targets ...Target) (*Sink, string) {
+	dir := t.TempDir()
+	data, err := json.Marshal(targets)
+	require.NoError(t, err)
+	targetsFile := filepath.Join(dir, "targets.json")
+	require.NoError(t, os.WriteFile(targetsFile, data, 0o600))
+
+	deadLetterFile := filepath.Join(dir, "dead.jsonl")
+	sink, err := NewSink(emptyLogger, WebhookConfig{
+		WebhookTargetsFile:    targetsFile,
+		WebhookSecret:         "secret",
+		WebhookMaxAttempts:    3,
+		WebhookBackoffMillis:  0,
+		WebhookTimeoutSeconds: 1,
+		WebhookQueueSize:      10,
+		WebhookDeadLetterFile: deadLetterFile,
+	}, metrics.Noop{})
+	require.NoError(t, err)
+
+	return sink, deadLetterFile
+}
+
+// runSink publishes the sessions to targets of the sink through an events fanout
+func runSink(t *testing.T, sink *Sink, sessions ...matchmaking.MatchSession) {
+	input := make(chan matchmaking.MatchSession, len(sessions))
+	for _, session := range sessions {
+		input <- session
+	}
+	close(input)
+	fanout := sink.AddTo(events.NewFanout(emptyLogger, "default", 10, metrics.Noop{}))
+	require.NoError(t, fanout.Run(t.Context(), input))
+}
+
+func readDeadLetters(t *testing.T, path string) []deadLetterRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []deadLetterRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record deadLetterRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSinkDeliversSignedEvents(t *testing.T) {
+	// Arrange
+	var l sync.Mutex
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		timestamp, _, _ := strings.Cut(strings.TrimPrefix(request.Header.Get(SignatureHeader), "t="), ",")
+		unix, _ := strconv.ParseInt(timestamp, 10, 64)
+		if request.Header.Get(SignatureHeader) != Sign("target-secret", unix, body) {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event Event
+		_ = json.Unmarshal(body, &event)
+		l.Lock()
+		received = append(received, event)
+		l.Unlock()
+	}))
+	defer server.Close()
+	sink, deadLetterFile := newTestSink(t, Target{URL: server.URL, Secret: "target-secret", Types: []string{matchmaking.ChangesTypeMatchFound}})
+	match := matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "1", Level: 1})
+
+	// Act
+	runSink(t, sink, matchmaking.NewMatchSession(matchmaking.ChangesTypeAdded, matchmaking.Player{ID: "1", Level: 1}), match)
+
+	// Assert
+	require.Len(t, received, 1)
+	assert.Equal(t, match.ID, received[0].ID)
+	assert.Equal(t, "default", received[0].Queue)
+	assert.Equal(t, match.Players, received[0].Players)
+	assert.NoFileExists(t, deadLetterFile)
+}
+
+func TestSinkRetriesFailedAttempts(t *testing.T) {
+	// Arrange
+	attempts := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if attempts.Add(1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "3", request.Header.Get(AttemptHeader))
+	}))
+	defer server.Close()
+	sink, deadLetterFile := newTestSink(t, Target{URL: server.URL})
+
+	// Act
+	runSink(t, sink, matchmaking.NewMatchSession(matchmaking.ChangesTypeTimeout, matchmaking.Player{ID: "1"}))
+
+	// Assert
+	assert.EqualValues(t, 3, attempts.Load())
+	assert.NoFileExists(t, deadLetterFile)
+}
+
+func TestSinkDeadLetters(t *testing.T) {
+	// Arrange
+	failing := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	rejectedAttempts := atomic.Int32{}
+	rejecting := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		rejectedAttempts.Add(1)
+		writer.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	sink, deadLetterFile := newTestSink(t, Target{URL: failing.URL}, Target{URL: rejecting.URL})
+	session := matchmaking.NewMatchSession(matchmaking.ChangesTypeRemoved, matchmaking.Player{ID: "1"})
+
+	// Act
+	runSink(t, sink, session)
+
+	// Assert
+	attemptsByURL := map[string]int{}
+	for _, record := range readDeadLetters(t, deadLetterFile) {
+		assert.Equal(t, session.ID, record.Event.ID)
+		attemptsByURL[record.URL] = record.Attempts
+	}
+	assert.Equal(t, map[string]int{failing.URL: 3, rejecting.URL: 1}, attemptsByURL)
+	assert.EqualValues(t, 1, rejectedAttempts.Load())
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	// Assert
+	assert.Equal(t, 500*time.Millisecond, retryBackoff(500*time.Millisecond, 0))
+	assert.Equal(t, 2*time.Second, retryBackoff(500*time.Millisecond, 2))
+	assert.Equal(t, maxBackoff, retryBackoff(500*time.Millisecond, 64))
+	assert.Equal(t, maxBackoff, retryBackoff(500*time.Millisecond, 1000))
+}
+
+func TestTargetsRequireSecret(t *testing.T) {
+	// Arrange
+	targetsFile := filepath.Join(t.TempDir(), "targets.json")
+	require.NoError(t, os.WriteFile(targetsFile, []byte(`[{"url":"http://localhost"}]`), 0o600))
+
+	// Act
+	_, err := WebhookConfig{WebhookTargetsFile: targetsFile}.Targets()
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestSinkDeadLettersDroppedEvents(t *testing.T) {
+	// Arrange
+	sink, deadLetterFile := newTestSink(t, Target{URL: "http://localhost", Types: []string{matchmaking.ChangesTypeMatchFound}})
+	publisher := &targetPublisher{sink: sink, target: sink.targets[0]}
+	added := events.NewEvent("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeAdded, matchmaking.Player{ID: "1"}))
+	matched := events.NewEvent("default", matchmaking.NewMatchSession(matchmaking.ChangesTypeMatchFound, matchmaking.Player{ID: "1"}))
+
+	// Act
+	publisher.Dropped(t.Context(), added, errors.New("queue is full"))
+	publisher.Dropped(t.Context(), matched, errors.New("queue is full"))
+	require.NoError(t, publisher.Close())
+
+	// Assert
+	records := readDeadLetters(t, deadLetterFile)
+	require.Len(t, records, 1)
+	assert.Equal(t, matched.ID, records[0].Event.ID)
+	assert.Zero(t, records[0].Attempts)
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const bearerPrefix = "Bearer "
+
+var (
+	ErrNoKeys       = errors.New("neither hmac secret nor rsa public key is configured")
+	ErrTokenMissing = errors.New("authorization token is not provided")
+)
+
+// publicMethodPrefixes are never authenticated
+var publicMethodPrefixes = []string{
+	"/grpc.health.v1.Health/",
+}
+
+type claimsKey struct{}
+
+// Claims of the token, the subject is the identity of the caller
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+// HasRole reports whether the caller has the role
+func (c *Claims) HasRole(role string) bool {
+	return role != "" && slices.Contains(c.Roles, role)
+}
+
+// Authenticator validates signed tokens with locally configured keys
+type Authenticator struct {
+	hmacSecret   []byte
+	rsaPublicKey *rsa.PublicKey
+	parser       *jwt.Parser
+}
+
+// NewAuthenticator creates an authenticator for HS256 and/or RS256 tokens
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{}
+	var methods []string
+	if config.AuthHmacSecret != "" {
+		a.hmacSecret = []byte(config.AuthHmacSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if config.AuthRsaPublicKeyFile != "" {
+		data, err := os.ReadFile(config.AuthRsaPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rsa public key: %w", err)
+		}
+		a.rsaPublicKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rsa public key: %w", err)
+		}
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(5 * time.Second),
+	}
+	if config.AuthIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.AuthIssuer))
+	}
+	if config.AuthAudience != "" {
+		opts = append(opts, jwt.WithAudience(config.AuthAudience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Verify parses the token and validates its signature and claims
+func (a *Authenticator) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := a.parser.ParseWithClaims(token, claims, a.key)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	return claims, nil
+}
+
+// VerifyRequest validates the bearer token from the Authorization header or the access_token query parameter
+func (a *Authenticator) VerifyRequest(request *http.Request) (*Claims, error) {
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), bearerPrefix)
+	if token == "" {
+		token = request.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		return nil, ErrTokenMissing
+	}
+
+	return a.Verify(token)
+}
+
+// UnaryServerInterceptor authenticates unary calls and stores claims in the context
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isPublicMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := a.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates streaming calls and stores claims in the context
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublicMethod(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := a.authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (a *Authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], bearerPrefix) {
+		return nil, status.Error(codes.Unauthenticated, ErrTokenMissing.Error())
+	}
+
+	claims, err := a.Verify(strings.TrimPrefix(values[0], bearerPrefix))
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+
+	return WithClaims(ctx, claims), nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return a.hmacSecret, nil
+	case *jwt.SigningMethodRSA:
+		return a.rsaPublicKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+// WithClaims returns a copy of ctx with the caller claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the caller claims if the call was authenticated
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// SignHmac signs claims with HS256, useful for tools and tests
+func SignHmac(secret string, claims Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func isPublicMethod(method string) bool {
+	return slices.ContainsFunc(publicMethodPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(method, prefix)
+	})
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+func signTestToken(t *testing.T, secret string, subject string, expiresIn time.Duration, roles ...string) string {
+	token, err := SignHmac(secret, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		},
+		Roles: roles,
+	})
+	assert.NoError(t, err)
+	return token
+}
+
+func TestVerifyHmacToken(t *testing.T) {
+	// Arrange
+	authenticator, err := NewAuthenticator(AuthConfig{AuthHmacSecret: testSecret})
+	assert.NoError(t, err)
+	token := signTestToken(t, testSecret, "player-1", time.Minute, "backend")
+
+	// Act
+	claims, err := authenticator.Verify(token)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "player-1", claims.Subject)
+	assert.True(t, claims.HasRole("backend"))
+	assert.False(t, claims.HasRole("admin"))
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	// Arrange
+	authenticator, err := NewAuthenticator(AuthConfig{AuthHmacSecret: testSecret})
+	assert.NoError(t, err)
+	tokens := map[string]string{
+		"wrong secret": signTestToken(t, "other-secret", "player-1", time.Minute),
+		"expired":      signTestToken(t, testSecret, "player-1", -time.Minute),
+		"no subject":   signTestToken(t, testSecret, "", time.Minute),
+		"garbage":      "not-a-token",
+	}
+
+	for name, token := range tokens {
+		// Act
+		_, err := authenticator.Verify(token)
+
+		// Assert
+		assert.Error(t, err, name)
+	}
+}
+
+func TestNewAuthenticatorWithoutKeys(t *testing.T) {
+	// Act
+	_, err := NewAuthenticator(AuthConfig{})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNoKeys)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	// Arrange
+	authenticator, err := NewAuthenticator(AuthConfig{AuthHmacSecret: testSecret})
+	assert.NoError(t, err)
+	interceptor := authenticator.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/matchmaking.Matchmaking/AddPlayer"}
+	var subject string
+	handler := func(ctx context.Context, _ any) (any, error) {
+		claims, _ := ClaimsFromContext(ctx)
+		subject = claims.Subject
+		return nil, nil
+	}
+	token := signTestToken(t, testSecret, "player-1", time.Minute)
+	authorizedCtx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer "+token))
+
+	// Act
+	_, errMissing := interceptor(t.Context(), nil, info, handler)
+	_, errHealth := interceptor(t.Context(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, func(context.Context, any) (any, error) {
+		return nil, nil
+	})
+	_, errAuthorized := interceptor(authorizedCtx, nil, info, handler)
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(errMissing))
+	assert.NoError(t, errHealth)
+	assert.NoError(t, errAuthorized)
+	assert.Equal(t, "player-1", subject)
+}
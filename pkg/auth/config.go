@@ -0,0 +1,9 @@
+package auth
+
+type AuthConfig struct {
+	AuthEnabled          bool   `env:"AUTH_ENABLED, default=false"`
+	AuthHmacSecret       string `env:"AUTH_HMAC_SECRET"`
+	AuthRsaPublicKeyFile string `env:"AUTH_RSA_PUBLIC_KEY_FILE"`
+	AuthIssuer           string `env:"AUTH_ISSUER"`
+	AuthAudience         string `env:"AUTH_AUDIENCE"`
+}
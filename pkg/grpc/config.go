@@ -1,6 +1,50 @@
 package grpc
 
+import (
+	"net"
+	"time"
+)
+
 type PublicGrpcConfig struct {
-	GrpcProtocol string `env:"GRPC_PROTOCOL, default=tcp"`
-	GrpcAddress  string `env:"GRPC_ADDRESS, default=:32023"`
+	GrpcProtocol         string `env:"GRPC_PROTOCOL, default=tcp"`
+	GrpcAddress          string `env:"GRPC_ADDRESS, default=:32023"`
+	GatewayAddress       string `env:"GATEWAY_ADDRESS, default=:8080"`
+	GrpcTlsCertFile      string `env:"GRPC_TLS_CERT_FILE"`
+	GrpcTlsKeyFile       string `env:"GRPC_TLS_KEY_FILE"`
+	GrpcTlsClientCAFile  string `env:"GRPC_TLS_CLIENT_CA_FILE"`
+	GrpcTlsReloadSeconds int    `env:"GRPC_TLS_RELOAD_SECONDS, default=60"`
+	GatewayTlsCertFile   string `env:"GRPC_GATEWAY_TLS_CERT_FILE"`
+	GatewayTlsKeyFile    string `env:"GRPC_GATEWAY_TLS_KEY_FILE"`
+	GatewayTlsCAFile     string `env:"GRPC_GATEWAY_TLS_CA_FILE"`
+	GatewayTlsServerName string `env:"GRPC_GATEWAY_TLS_SERVER_NAME"`
+	GrpcRecoveryEnabled  bool   `env:"GRPC_RECOVERY_ENABLED, default=true"`
+	GrpcAccessLogEnabled bool   `env:"GRPC_ACCESS_LOG_ENABLED, default=true"`
+	GrpcMetricsEnabled   bool   `env:"GRPC_METRICS_ENABLED, default=true"`
+}
+
+// TlsEnabled reports whether the server certificate is configured
+func (c PublicGrpcConfig) TlsEnabled() bool {
+	return c.GrpcTlsCertFile != "" && c.GrpcTlsKeyFile != ""
+}
+
+// MutualTlsEnabled reports whether client certificates are required
+func (c PublicGrpcConfig) MutualTlsEnabled() bool {
+	return c.TlsEnabled() && c.GrpcTlsClientCAFile != ""
+}
+
+// GatewayServerName name the gateway expects in the gRPC server certificate, the host of the gRPC address by default
+func (c PublicGrpcConfig) GatewayServerName() string {
+	if c.GatewayTlsServerName != "" {
+		return c.GatewayTlsServerName
+	}
+	host, _, err := net.SplitHostPort(c.GrpcAddress)
+	if c.GrpcProtocol == "unix" || err != nil || host == "" {
+		return "localhost"
+	}
+
+	return host
+}
+
+func (c PublicGrpcConfig) TlsReloadInterval() time.Duration {
+	return time.Duration(c.GrpcTlsReloadSeconds) * time.Second
 }
@@ -2,37 +2,109 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"log/slog"
 	"math"
 	"net"
+	"net/http"
+	"strings"
+	"time"
 )
 
 var maxSendMsgSize = grpc.MaxSendMsgSize(math.MaxInt32)
 
-type ServerRegistrationFunc func(registrar grpc.ServiceRegistrar, mux *runtime.ServeMux) error
+// errGatewayCertificateRequired is returned when the gateway must present a client certificate but has none
+var errGatewayCertificateRequired = errors.New("gateway client certificate is required with mTLS, set GRPC_GATEWAY_TLS_CERT_FILE and GRPC_GATEWAY_TLS_KEY_FILE")
+
+// ServerRegistrationFunc registers gateway handlers which proxy HTTP/JSON requests to the gRPC server through conn
+type ServerRegistrationFunc func(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error
+
+type gatewayHandler struct {
+	method  string
+	path    string
+	handler runtime.HandlerFunc
+}
 
 type Server struct {
-	logger    *slog.Logger
-	config    PublicGrpcConfig
-	grpcSever *grpc.Server
+	logger          *slog.Logger
+	config          PublicGrpcConfig
+	grpcSever       *grpc.Server
+	gatewayFuncs    []ServerRegistrationFunc
+	gatewayHandlers []gatewayHandler
+	gatewayMux      *runtime.ServeMux
+	gatewayServer   *http.Server
+	certReloader    *certReloader
+	metrics         *rpcMetrics
+	health          *health.Server
 }
 
-// NewGRPC new grpc server
-func NewGRPC(logger *slog.Logger, config PublicGrpcConfig) *Server {
+// NewGRPC new grpc server with metrics, access log and recovery interceptors,
+// extra options such as interceptors are applied after the defaults
+func NewGRPC(logger *slog.Logger, config PublicGrpcConfig, extraOpts ...grpc.ServerOption) (*Server, error) {
+	s := &Server{
+		logger:     logger,
+		config:     config,
+		metrics:    newRpcMetrics(),
+		gatewayMux: runtime.NewServeMux(),
+	}
+	// the gateway server exists from the start, so Shutdown closes it even before the gateway listens
+	s.gatewayServer = &http.Server{
+		Addr:              config.GatewayAddress,
+		Handler:           s.gatewayMux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
 	opts := []grpc.ServerOption{
 		maxSendMsgSize,
 	}
+	if config.TlsEnabled() {
+		reloader, err := newCertReloader(logger, config)
+		if err != nil {
+			return nil, err
+		}
+		s.certReloader = reloader
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			GetConfigForClient: reloader.GetConfigForClient,
+		})))
+		// the gateway verifies client certificates like the grpc server, see certReloader
+		s.gatewayServer.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			GetConfigForClient: reloader.GetGatewayConfigForClient,
+		}
+	}
+	opts = append(opts, s.interceptors()...)
+	opts = append(opts, extraOpts...)
+
+	s.grpcSever = grpc.NewServer(opts...)
+
+	return s, nil
+}
 
-	return &Server{
-		logger:    logger,
-		config:    config,
-		grpcSever: grpc.NewServer(opts...),
+// RegisterMetricsOn registers RPC metrics on the registerer
+func (s *Server) RegisterMetricsOn(registerer prometheusclient.Registerer) error {
+	for _, collector := range []prometheusclient.Collector{s.metrics.handled, s.metrics.duration} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
 	}
+
+	return nil
+}
+
+// UnRegisterFrom unregisters RPC metrics from the registerer
+func (s *Server) UnRegisterFrom(registerer prometheusclient.Registerer) {
+	registerer.Unregister(s.metrics.handled)
+	registerer.Unregister(s.metrics.duration)
 }
 
 // AddServerImplementation adds server implementation
@@ -41,20 +113,76 @@ func (s *Server) AddServerImplementation(regFunc func(registrar grpc.ServiceRegi
 	return s
 }
 
-// AddGrpcHealthCheck adds grpc health check
+// AddGatewayImplementation adds HTTP/JSON gateway handlers for a server implementation
+func (s *Server) AddGatewayImplementation(regFunc ServerRegistrationFunc) *Server {
+	s.gatewayFuncs = append(s.gatewayFuncs, regFunc)
+	return s
+}
+
+// AddGatewayHandler adds a plain HTTP handler to the gateway
+func (s *Server) AddGatewayHandler(method, path string, handler runtime.HandlerFunc) *Server {
+	s.gatewayHandlers = append(s.gatewayHandlers, gatewayHandler{
+		method:  method,
+		path:    path,
+		handler: handler,
+	})
+	return s
+}
+
+// AddGrpcHealthCheck adds grpc health check, the server is serving until SetServing changes it
 func (s *Server) AddGrpcHealthCheck() *Server {
-	hs := health.NewServer()
-	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
-	healthpb.RegisterHealthServer(s.grpcSever, hs)
+	s.health = health.NewServer()
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s.grpcSever, s.health)
 	return s
 }
 
-// ListenAndServe start grpc-gateway
+// SetServing changes the status reported by the grpc health check
+func (s *Server) SetServing(serving bool) {
+	if s.health == nil {
+		return
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if !serving {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.health.SetServingStatus("", status)
+}
+
+// RunHealthCheck reports the server as not serving while the check fails, the check runs every interval until ctx is done
+func (s *Server) RunHealthCheck(ctx context.Context, interval time.Duration, check func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	serving := true
+	for {
+		err := check()
+		if (err == nil) != serving {
+			serving = err == nil
+			if serving {
+				s.logger.InfoContext(ctx, "gRPC health check is serving")
+			} else {
+				s.logger.WarnContext(ctx, "gRPC health check is not serving", slog.String("error", err.Error()))
+			}
+			s.SetServing(serving)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListenAndServe start grpc server
 func (s *Server) ListenAndServe() error {
 	network := s.config.GrpcProtocol
 	address := s.config.GrpcAddress
 
-	s.logger.Info("Starting gRPC server", slog.String("grpc", fmt.Sprintf("%v:%v", network, address)))
+	s.logger.Info("Starting gRPC server", slog.String("grpc", fmt.Sprintf("%v:%v", network, address)),
+		slog.Bool("tls", s.config.TlsEnabled()), slog.Bool("mtls", s.config.MutualTlsEnabled()))
 
 	l, err := net.Listen(network, address)
 	if err != nil {
@@ -64,8 +192,106 @@ func (s *Server) ListenAndServe() error {
 	return s.grpcSever.Serve(l)
 }
 
-// Shutdown to run on app shutdown
-func (s *Server) Shutdown(_ context.Context) error {
-	s.grpcSever.GracefulStop()
-	return nil
+// ListenAndServeGateway start grpc-gateway which proxies HTTP/JSON requests to the grpc server,
+// returns nil without listening when the server is already shut down
+func (s *Server) ListenAndServeGateway(ctx context.Context) error {
+	if len(s.gatewayFuncs) == 0 && len(s.gatewayHandlers) == 0 {
+		return nil
+	}
+
+	creds, err := s.gatewayCredentials()
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.NewClient(s.dialTarget(), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("failed to create gateway client: %w", err)
+	}
+	defer conn.Close()
+
+	for _, regFunc := range s.gatewayFuncs {
+		if err := regFunc(ctx, s.gatewayMux, conn); err != nil {
+			return fmt.Errorf("failed to register gateway: %w", err)
+		}
+	}
+	for _, h := range s.gatewayHandlers {
+		if err := s.gatewayMux.HandlePath(h.method, h.path, h.handler); err != nil {
+			return fmt.Errorf("failed to register gateway handler %s %s: %w", h.method, h.path, err)
+		}
+	}
+
+	if s.certReloader == nil {
+		s.logger.Info("Starting gRPC gateway", slog.String("url", fmt.Sprintf("http://%s", prettyAddress(s.config.GatewayAddress))))
+		err = s.gatewayServer.ListenAndServe()
+	} else {
+		s.logger.Info("Starting gRPC gateway", slog.String("url", fmt.Sprintf("https://%s", prettyAddress(s.config.GatewayAddress))),
+			slog.Bool("mtls", s.config.MutualTlsEnabled()))
+		err = s.gatewayServer.ListenAndServeTLS("", "")
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown to run on app shutdown, waits for running calls until ctx is done and then closes them
+func (s *Server) Shutdown(ctx context.Context) error {
+	// health check reports not serving from now on
+	if s.health != nil {
+		s.health.Shutdown()
+	}
+
+	err := s.gatewayServer.Shutdown(ctx)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.grpcSever.GracefulStop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcSever.Stop()
+		<-stopped
+		err = errors.Join(err, ctx.Err())
+	}
+
+	return err
+}
+
+// gatewayCredentials returns credentials for the gateway connection to the grpc server in the same process,
+// the server certificate is verified by GRPC_GATEWAY_TLS_CA_FILE and with mTLS the gateway presents its own certificate
+func (s *Server) gatewayCredentials() (credentials.TransportCredentials, error) {
+	if s.certReloader == nil {
+		return insecure.NewCredentials(), nil
+	}
+	if s.config.MutualTlsEnabled() && (s.config.GatewayTlsCertFile == "" || s.config.GatewayTlsKeyFile == "") {
+		return nil, errGatewayCertificateRequired
+	}
+
+	config, err := NewClientTlsConfig(s.config.GatewayTlsCAFile, s.config.GatewayTlsCertFile, s.config.GatewayTlsKeyFile, s.config.GatewayServerName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gateway tls config: %w", err)
+	}
+
+	return credentials.NewTLS(config), nil
+}
+
+// dialTarget returns the address the gateway uses to reach the grpc server
+func (s *Server) dialTarget() string {
+	if s.config.GrpcProtocol == "unix" {
+		return "unix:" + s.config.GrpcAddress
+	}
+
+	return prettyAddress(s.config.GrpcAddress)
+}
+
+func prettyAddress(address string) string {
+	if strings.HasPrefix(address, ":") {
+		return fmt.Sprintf("localhost%s", address)
+	}
+
+	return address
 }
@@ -0,0 +1,33 @@
+package grpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdownBeforeGatewayListens(t *testing.T) {
+	// Arrange
+	config := PublicGrpcConfig{GrpcProtocol: "tcp", GrpcAddress: freeAddress(t), GatewayAddress: freeAddress(t)}
+	server, err := NewGRPC(emptyLogger, config)
+	require.NoError(t, err)
+	server.AddGatewayHandler(http.MethodGet, "/ping", func(writer http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		writer.WriteHeader(http.StatusNoContent)
+	})
+
+	// Act
+	errShutdown := server.Shutdown(t.Context())
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServeGateway(t.Context()) }()
+
+	// Assert
+	assert.NoError(t, errShutdown)
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("gateway serves after shutdown")
+	}
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	rpcTypeUnary  = "unary"
+	rpcTypeStream = "stream"
+)
+
+// rpcMetrics per-RPC counters and latency histograms
+type rpcMetrics struct {
+	handled  *prometheusclient.CounterVec
+	duration *prometheusclient.HistogramVec
+}
+
+func newRpcMetrics() *rpcMetrics {
+	return &rpcMetrics{
+		handled: prometheusclient.NewCounterVec(prometheusclient.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of RPCs completed on the server, regardless of success or failure.",
+		}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"}),
+		duration: prometheusclient.NewHistogramVec(prometheusclient.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Histogram of response latency of RPCs handled by the server.",
+			Buckets: prometheusclient.DefBuckets,
+		}, []string{"grpc_type", "grpc_service", "grpc_method"}),
+	}
+}
+
+func (m *rpcMetrics) observe(rpcType string, fullMethod string, code codes.Code, duration time.Duration) {
+	service, method := splitMethodName(fullMethod)
+	m.handled.WithLabelValues(rpcType, service, method, code.String()).Inc()
+	m.duration.WithLabelValues(rpcType, service, method).Observe(duration.Seconds())
+}
+
+// interceptors returns the configured chain: metrics, access log and panic recovery, in this order
+func (s *Server) interceptors() []grpc.ServerOption {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if s.config.GrpcMetricsEnabled {
+		unary = append(unary, s.metricsUnaryInterceptor)
+		stream = append(stream, s.metricsStreamInterceptor)
+	}
+	if s.config.GrpcAccessLogEnabled {
+		unary = append(unary, s.loggingUnaryInterceptor)
+		stream = append(stream, s.loggingStreamInterceptor)
+	}
+	if s.config.GrpcRecoveryEnabled {
+		unary = append(unary, s.recoveryUnaryInterceptor)
+		stream = append(stream, s.recoveryStreamInterceptor)
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+func (s *Server) metricsUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.metrics.observe(rpcTypeUnary, info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+func (s *Server) metricsStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.metrics.observe(rpcTypeStream, info.FullMethod, status.Code(err), time.Since(start))
+	return err
+}
+
+func (s *Server) loggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.logCall(ctx, rpcTypeUnary, info.FullMethod, err, time.Since(start))
+	return resp, err
+}
+
+func (s *Server) loggingStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.logCall(stream.Context(), rpcTypeStream, info.FullMethod, err, time.Since(start))
+	return err
+}
+
+func (s *Server) recoveryUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.recovered(ctx, info.FullMethod, r)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func (s *Server) recoveryStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.recovered(stream.Context(), info.FullMethod, r)
+		}
+	}()
+
+	return handler(srv, stream)
+}
+
+// recovered logs the panic with stack trace and hides details from the caller
+func (s *Server) recovered(ctx context.Context, fullMethod string, r any) error {
+	s.logger.ErrorContext(ctx, "gRPC handler panic",
+		slog.String("method", fullMethod),
+		slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())))
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func (s *Server) logCall(ctx context.Context, rpcType string, fullMethod string, err error, duration time.Duration) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("type", rpcType),
+		slog.String("method", fullMethod),
+		slog.String("code", code.String()),
+		slog.Duration("duration", duration),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	s.logger.LogAttrs(ctx, logLevel(code), "gRPC call", attrs...)
+}
+
+// logLevel returns error level for codes which point to server side problems
+func logLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// splitMethodName splits "/package.Service/Method" into service and method
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "unknown", fullMethod
+}
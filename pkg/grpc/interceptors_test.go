@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	prometheusclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestRecoveryInterceptorReturnsInternal(t *testing.T) {
+	// Arrange
+	server, err := NewGRPC(emptyLogger, PublicGrpcConfig{GrpcRecoveryEnabled: true})
+	assert.NoError(t, err)
+	info := &grpc.UnaryServerInfo{FullMethod: "/matchmaking.Matchmaking/AddPlayer"}
+
+	// Act
+	_, err = server.recoveryUnaryInterceptor(t.Context(), nil, info, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+
+	// Assert
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestMetricsInterceptorCountsCodes(t *testing.T) {
+	// Arrange
+	server, err := NewGRPC(emptyLogger, PublicGrpcConfig{GrpcMetricsEnabled: true})
+	assert.NoError(t, err)
+	registry := prometheusclient.NewRegistry()
+	assert.NoError(t, server.RegisterMetricsOn(registry))
+	info := &grpc.UnaryServerInfo{FullMethod: "/matchmaking.Matchmaking/AddPlayer"}
+
+	// Act
+	for _, code := range []codes.Code{codes.OK, codes.OK, codes.InvalidArgument} {
+		_, _ = server.metricsUnaryInterceptor(t.Context(), nil, info, func(context.Context, any) (any, error) {
+			return nil, status.Error(code, "")
+		})
+	}
+
+	// Assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(server.metrics.handled.WithLabelValues(rpcTypeUnary, "matchmaking.Matchmaking", "AddPlayer", codes.OK.String())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(server.metrics.handled.WithLabelValues(rpcTypeUnary, "matchmaking.Matchmaking", "AddPlayer", codes.InvalidArgument.String())))
+}
@@ -0,0 +1,190 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+var errNoCertificates = errors.New("no certificates found")
+
+// certReloader keeps the server certificate and client CA up to date with files on disk,
+// files are checked on handshake at most once per interval
+type certReloader struct {
+	logger    *slog.Logger
+	certFile  string
+	keyFile   string
+	caFile    string
+	interval  time.Duration
+	l         sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTime   time.Time
+	checked   time.Time
+}
+
+func newCertReloader(logger *slog.Logger, config PublicGrpcConfig) (*certReloader, error) {
+	r := &certReloader{
+		logger:   logger,
+		certFile: config.GrpcTlsCertFile,
+		keyFile:  config.GrpcTlsKeyFile,
+		caFile:   config.GrpcTlsClientCAFile,
+		interval: config.TlsReloadInterval(),
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetConfigForClient returns TLS config with the current certificate for every handshake
+func (r *certReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return r.configForClient("h2"), nil
+}
+
+// GetGatewayConfigForClient returns TLS config of the gateway listener, which serves HTTP/1.1 for WebSocket too
+func (r *certReloader) GetGatewayConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return r.configForClient("h2", "http/1.1"), nil
+}
+
+// configForClient returns TLS config with the current certificate, client certificates are required with the client CA
+func (r *certReloader) configForClient(nextProtos ...string) *tls.Config {
+	r.maybeReload()
+
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.cert},
+		NextProtos:   nextProtos,
+	}
+	if r.clientCAs != nil {
+		config.ClientCAs = r.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config
+}
+
+func (r *certReloader) maybeReload() {
+	r.l.RLock()
+	due := r.interval > 0 && time.Since(r.checked) >= r.interval
+	r.l.RUnlock()
+	if !due {
+		return
+	}
+
+	modTime, err := r.lastModified()
+	if err != nil {
+		r.logger.Error("failed to check certificates", slog.String("error", err.Error()))
+		return
+	}
+
+	r.l.Lock()
+	r.checked = time.Now()
+	changed := modTime.After(r.modTime)
+	r.l.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := r.reload(); err != nil {
+		r.logger.Error("failed to reload certificates, keep using previous ones", slog.String("error", err.Error()))
+		return
+	}
+	r.logger.Info("Certificates reloaded", slog.String("cert", r.certFile))
+}
+
+func (r *certReloader) reload() error {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.caFile != "" {
+		clientCAs, err = loadCertPool(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client CA: %w", err)
+		}
+	}
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTime = modTime
+	r.checked = time.Now()
+
+	return nil
+}
+
+// lastModified returns the latest modification time among watched files
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// NewClientTlsConfig creates client TLS config, caFile verifies the server and certFile/keyFile are presented for mTLS
+func NewClientTlsConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if caFile != "" {
+		rootCAs, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA: %w", err)
+		}
+		config.RootCAs = rootCAs
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: %w", file, errNoCertificates)
+	}
+
+	return pool, nil
+}
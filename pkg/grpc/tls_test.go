@@ -0,0 +1,291 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var emptyLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+// writeSelfSignedCert writes a self-signed certificate usable as CA, server and client certificate
+func writeSelfSignedCert(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	assert.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	assert.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func leafSerial(t *testing.T, config *tls.Config) int64 {
+	leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	assert.NoError(t, err)
+	return leaf.SerialNumber.Int64()
+}
+
+func TestCertReloaderReloadsChangedFiles(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	now := time.Now()
+	writeSelfSignedCert(t, certFile, keyFile, 1, now.Add(-time.Minute))
+	reloader, err := newCertReloader(emptyLogger, PublicGrpcConfig{
+		GrpcTlsCertFile:      certFile,
+		GrpcTlsKeyFile:       keyFile,
+		GrpcTlsClientCAFile:  certFile,
+		GrpcTlsReloadSeconds: 1,
+	})
+	assert.NoError(t, err)
+	before, err := reloader.GetConfigForClient(nil)
+	assert.NoError(t, err)
+
+	// Act
+	writeSelfSignedCert(t, certFile, keyFile, 2, now)
+	reloader.checked = now.Add(-time.Minute)
+	after, err := reloader.GetConfigForClient(nil)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, int64(1), leafSerial(t, before))
+	assert.Equal(t, int64(2), leafSerial(t, after))
+	assert.Equal(t, tls.RequireAndVerifyClientCert, after.ClientAuth)
+	assert.Contains(t, after.NextProtos, "h2")
+}
+
+func TestCertReloaderKeepsPreviousCertOnError(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	now := time.Now()
+	writeSelfSignedCert(t, certFile, keyFile, 1, now.Add(-time.Minute))
+	reloader, err := newCertReloader(emptyLogger, PublicGrpcConfig{
+		GrpcTlsCertFile:      certFile,
+		GrpcTlsKeyFile:       keyFile,
+		GrpcTlsReloadSeconds: 1,
+	})
+	assert.NoError(t, err)
+
+	// Act
+	assert.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	reloader.checked = now.Add(-time.Minute)
+	config, err := reloader.GetConfigForClient(nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), leafSerial(t, config))
+	assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+}
+
+// testCA issues certificates for localhost signed by a self-signed CA
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	file := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	return &testCA{dir: dir, cert: cert, key: key, file: file}
+}
+
+// issue writes a certificate and key of the name and returns their files
+func (ca *testCA) issue(t *testing.T, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(ca.dir, name+".crt")
+	keyFile := filepath.Join(ca.dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile
+}
+
+func freeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// newMutualTlsConfig returns a config of the grpc server and gateway with mTLS by the CA
+func newMutualTlsConfig(t *testing.T, ca *testCA) PublicGrpcConfig {
+	serverCert, serverKey := ca.issue(t, "server", 2)
+	gatewayCert, gatewayKey := ca.issue(t, "gateway", 3)
+	return PublicGrpcConfig{
+		GrpcProtocol:        "tcp",
+		GrpcAddress:         freeAddress(t),
+		GatewayAddress:      freeAddress(t),
+		GrpcTlsCertFile:     serverCert,
+		GrpcTlsKeyFile:      serverKey,
+		GrpcTlsClientCAFile: ca.file,
+		GatewayTlsCertFile:  gatewayCert,
+		GatewayTlsKeyFile:   gatewayKey,
+		GatewayTlsCAFile:    ca.file,
+	}
+}
+
+func TestGatewayRequiresClientCertificate(t *testing.T) {
+	// Arrange
+	ca := newTestCA(t)
+	config := newMutualTlsConfig(t, ca)
+	server, err := NewGRPC(emptyLogger, config)
+	require.NoError(t, err)
+	server.AddGatewayHandler(http.MethodGet, "/ping", func(writer http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		writer.WriteHeader(http.StatusNoContent)
+	})
+	go func() { _ = server.ListenAndServeGateway(t.Context()) }()
+	t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+	clientCert, clientKey := ca.issue(t, "player", 4)
+	withCert, err := NewClientTlsConfig(ca.file, clientCert, clientKey, "localhost")
+	require.NoError(t, err)
+	withoutCert, err := NewClientTlsConfig(ca.file, "", "", "localhost")
+	require.NoError(t, err)
+	get := func(scheme string, tlsConfig *tls.Config) (int, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}, Timeout: time.Second}
+		resp, err := client.Get(scheme + "://" + config.GatewayAddress + "/ping")
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	require.Eventually(t, func() bool {
+		status, err := get("https", withCert)
+		return err == nil && status == http.StatusNoContent
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Act
+	plainStatus, _ := get("http", nil)
+	_, errWithoutCert := get("https", withoutCert)
+
+	// Assert
+	assert.NotEqual(t, http.StatusNoContent, plainStatus)
+	assert.Error(t, errWithoutCert)
+}
+
+func TestGatewayVerifiesGrpcServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverName string
+		serving    bool
+	}{
+		{name: "server name of address", serving: true},
+		{name: "other server name", serverName: "other.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ca := newTestCA(t)
+			config := newMutualTlsConfig(t, ca)
+			config.GatewayTlsServerName = tt.serverName
+			server, err := NewGRPC(emptyLogger, config)
+			require.NoError(t, err)
+			server.AddGrpcHealthCheck()
+			go func() { _ = server.ListenAndServe() }()
+			t.Cleanup(func() { _ = server.Shutdown(context.Background()) })
+			creds, err := server.gatewayCredentials()
+			require.NoError(t, err)
+			conn, err := grpc.NewClient(server.dialTarget(), grpc.WithTransportCredentials(creds))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			// Act
+			ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+			defer cancel()
+			resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(tt.serving))
+
+			// Assert
+			if tt.serving {
+				require.NoError(t, err)
+				assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGatewayCertificateRequiredWithMutualTls(t *testing.T) {
+	// Arrange
+	config := newMutualTlsConfig(t, newTestCA(t))
+	config.GatewayTlsCertFile = ""
+	server, err := NewGRPC(emptyLogger, config)
+	require.NoError(t, err)
+	server.AddGatewayHandler(http.MethodGet, "/ping", func(http.ResponseWriter, *http.Request, map[string]string) {})
+
+	// Act
+	err = server.ListenAndServeGateway(t.Context())
+
+	// Assert
+	assert.ErrorIs(t, err, errGatewayCertificateRequired)
+}
@@ -1,18 +1,24 @@
 package logger
 
 import (
+	"io"
 	"log/slog"
 	"os"
 )
 
 func NewLogger(logLevel string) *slog.Logger {
+	return NewWriterLogger(logLevel, os.Stdout)
+}
+
+// NewWriterLogger writes JSON logs to the writer instead of the standard output
+func NewWriterLogger(logLevel string, writer io.Writer) *slog.Logger {
 	level := toSlogLevel(logLevel)
 
 	opts := &slog.HandlerOptions{
 		AddSource: true,
 		Level:     level,
 	}
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	handler := slog.NewJSONHandler(writer, opts)
 	logger := slog.New(handler)
 
 	slog.SetDefault(logger)
@@ -0,0 +1,7 @@
+package tracing
+
+type TracingConfig struct {
+	TracingExporter    string  `env:"TRACING_EXPORTER, default=none"`
+	TracingServiceName string  `env:"TRACING_SERVICE_NAME, default=matchmaking"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO, default=1"`
+}